@@ -7,6 +7,7 @@ import (
 
 	"chat-room/config"              // 引入项目的配置包，用于获取配置参数
 	"chat-room/internal/service"    // 引入服务层，用于调用业务逻辑
+	"chat-room/pkg/common/constant" // 引入常量包，定义了上下文键名
 	"chat-room/pkg/common/response" // 引入通用响应包，用于统一格式化HTTP响应
 	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
 
@@ -26,22 +27,32 @@ func GetFile(c *gin.Context) {
 func SaveFile(c *gin.Context) {
 	namePreffix := uuid.New().String() // 生成一个新的UUID作为文件名前缀，确保文件名唯一
 
-	userUuid := c.PostForm("uuid") // 从POST请求中获取用户UUID，用于关联用户信息
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 只能修改当前登录用户的头像，不再使用表单中的uuid
 
-	file, _ := c.FormFile("file")             // 从请求中获取上传的文件
+	file, err := c.FormFile("file") // 从请求中获取上传的文件
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("请选择要上传的文件")) // 没有上传文件时返回错误信息
+		return
+	}
 	fileName := file.Filename                 // 获取原始文件名
 	index := strings.LastIndex(fileName, ".") // 找到文件名中最后一个'.'的位置，用于获取文件后缀
-	suffix := fileName[index:]                // 提取文件后缀名
+	suffix := ""
+	if index >= 0 {
+		suffix = fileName[index:] // 提取文件后缀名，没有后缀时为空
+	}
 
 	newFileName := namePreffix + suffix // 将UUID和后缀拼接成新的文件名
 
 	log.Logger.Info("file", log.Any("file name", config.GetConfig().StaticPath.FilePath+newFileName)) // 记录新文件名到日志中
 	log.Logger.Info("userUuid", log.Any("userUuid name", userUuid))                                   // 记录关联的用户UUID到日志中
 
-	c.SaveUploadedFile(file, config.GetConfig().StaticPath.FilePath+newFileName) // 将上传的文件保存到服务器指定路径
-	err := service.UserService.ModifyUserAvatar(newFileName, userUuid)           // 调用业务逻辑层，更新用户的头像信息
-	if err != nil {
+	if err := c.SaveUploadedFile(file, config.GetConfig().StaticPath.FilePath+newFileName); err != nil { // 将上传的文件保存到服务器指定路径
+		c.JSON(http.StatusOK, response.FailMsg("文件保存失败")) // 如果保存失败，返回失败信息
+		return
+	}
+	if err := service.UserService.ModifyUserAvatar(newFileName, userUuid); err != nil { // 调用业务逻辑层，更新用户的头像信息
 		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果更新失败，返回失败信息
+		return
 	}
 	c.JSON(http.StatusOK, response.SuccessMsg(newFileName)) // 更新成功，返回新文件名作为响应
 }
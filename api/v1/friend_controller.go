@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"net/http" // 提供HTTP客户端和服务端的功能
+	"strconv"  // 引入字符串转换包，用于解析好友请求的id
+
+	"chat-room/internal/server"     // 引入服务器包，用于发布好友通知
+	"chat-room/internal/service"    // 引入服务层，用于调用业务逻辑
+	"chat-room/pkg/common/constant" // 引入常量包，定义了上下文键名和好友通知的事件类型
+	"chat-room/pkg/common/request"  // 引入通用请求包，定义了请求参数结构体
+	"chat-room/pkg/common/response" // 引入通用响应包，用于统一格式化HTTP响应
+
+	"github.com/gin-gonic/gin" // 引入Gin框架，用于处理HTTP请求
+)
+
+// AddFriend 函数用于处理添加好友请求，向对方发送好友请求，对方通过后双方成为好友
+func AddFriend(c *gin.Context) {
+	var userFriendRequest request.FriendRequest                      // 声明一个FriendRequest类型的变量，用于接收客户端发送的好友请求信息
+	c.ShouldBindJSON(&userFriendRequest)                             // 将请求中的JSON数据绑定到userFriendRequest变量
+	userFriendRequest.Uuid = c.GetString(constant.CONTEXT_USER_UUID) // 好友请求总是由当前登录用户发出
+
+	friendRequest, err := service.UserService.AddFriend(&userFriendRequest) // 调用服务层的AddFriend方法发送好友请求
+	if nil != err {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果发送失败，返回错误信息
+		return
+	}
+
+	server.PublishFriendEvent(userFriendRequest.Uuid, friendRequest.Uuid, friendRequest.Id, constant.FRIEND_EVENT_REQUEST) // 通知对方收到好友请求
+	c.JSON(http.StatusOK, response.SuccessMsg(friendRequest))                                                              // 返回待处理的好友请求
+}
+
+// GetFriendRequests 函数用于获取当前用户收到和发出的待处理好友请求
+func GetFriendRequests(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)              // 从上下文中获取当前用户的UUID
+	requests, err := service.UserService.GetFriendRequests(userUuid) // 调用服务层方法获取待处理的好友请求
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(requests)) // 返回好友请求列表
+}
+
+// AcceptFriendRequest 函数用于接收者通过好友请求
+func AcceptFriendRequest(c *gin.Context) {
+	handleFriendRequest(c, service.UserService.AcceptFriendRequest, constant.FRIEND_EVENT_ACCEPT)
+}
+
+// RejectFriendRequest 函数用于接收者拒绝好友请求
+func RejectFriendRequest(c *gin.Context) {
+	handleFriendRequest(c, service.UserService.RejectFriendRequest, constant.FRIEND_EVENT_REJECT)
+}
+
+// CancelFriendRequest 函数用于发送者取消好友请求
+func CancelFriendRequest(c *gin.Context) {
+	handleFriendRequest(c, service.UserService.CancelFriendRequest, constant.FRIEND_EVENT_CANCEL)
+}
+
+// handleFriendRequest 函数使用handle处理路径中指定的好友请求，成功后通知请求的另一方
+func handleFriendRequest(c *gin.Context, handle func(userUuid string, requestId int32) (string, error), event string) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	requestId, err := strconv.Atoi(c.Param("id"))       // 从请求路径中获取好友请求的id
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("好友请求不存在")) // id不是数字时请求不存在
+		return
+	}
+	otherUuid, err := handle(userUuid, int32(requestId)) // 处理好友请求，返回请求另一方的UUID
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果处理失败，返回错误信息
+		return
+	}
+	server.PublishFriendEvent(userUuid, otherUuid, int32(requestId), event) // 通知请求的另一方
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                         // 返回成功响应
+}
+
+// DeleteFriend 函数用于删除好友，双方的好友关系都会被删除
+func DeleteFriend(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	friendUuid := c.Param("uuid")                       // 从请求路径中获取好友的UUID
+	if err := service.UserService.DeleteFriend(userUuid, friendUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果删除失败，返回错误信息
+		return
+	}
+	server.PublishFriendEvent(userUuid, friendUuid, 0, constant.FRIEND_EVENT_DELETE) // 通知对方已被删除好友
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                  // 返回成功响应
+}
@@ -2,16 +2,20 @@ package v1
 
 import (
 	"chat-room/internal/model"      // 引入数据模型包，定义了数据库表结构
+	"chat-room/internal/server"     // 引入服务器包，用于发布群组系统消息
 	"chat-room/internal/service"    // 引入服务层，用于调用业务逻辑
+	"chat-room/pkg/common/constant" // 引入常量包，定义了群成员角色和群组事件
+	"chat-room/pkg/common/request"  // 引入请求参数包，用于绑定禁言时长、加群和邀请参数
 	"chat-room/pkg/common/response" // 引入通用响应包，用于统一格式化HTTP响应
 	"net/http"                      // 提供HTTP客户端和服务端的功能
+	"strconv"                       // 引入字符串转换包，用于解析加群申请的id
 
 	"github.com/gin-gonic/gin" // 引入Gin框架，用于处理HTTP请求
 )
 
-// GetGroup 函数用于获取某个用户的分组列表
+// GetGroup 函数用于获取当前登录用户的分组列表，路径中的UUID不再作为查询依据
 func GetGroup(c *gin.Context) {
-	uuid := c.Param("uuid")                             // 从请求路径中获取用户的UUID
+	uuid := c.GetString(constant.CONTEXT_USER_UUID)     // 从上下文中获取当前用户的UUID
 	groups, err := service.GroupService.GetGroups(uuid) // 调用服务层方法，获取用户的分组列表
 	if err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
@@ -21,31 +25,286 @@ func GetGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, response.SuccessMsg(groups)) // 返回分组列表信息，响应成功
 }
 
-// SaveGroup 函数用于保存用户的分组信息
+// SaveGroup 函数用于保存用户的分组信息，群主总是当前登录用户，路径中的UUID不再作为创建者
 func SaveGroup(c *gin.Context) {
-	uuid := c.Param("uuid")  // 从请求路径中获取用户的UUID
-	var group model.Group    // 声明一个Group类型的变量，用于接收客户端发送的分组信息
-	c.ShouldBindJSON(&group) // 将请求中的JSON数据绑定到group变量上
+	uuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	var group model.Group                           // 声明一个Group类型的变量，用于接收客户端发送的分组信息
+	c.ShouldBindJSON(&group)                        // 将请求中的JSON数据绑定到group变量上
 
 	service.GroupService.SaveGroup(uuid, group)     // 调用服务层方法，保存分组信息
 	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
 }
 
-// JoinGroup 函数用于将用户加入某个组
+// JoinGroup 函数用于当前用户申请加入某个组，需要审核的群组会创建加群申请并通知群主和管理员
 func JoinGroup(c *gin.Context) {
-	userUuid := c.Param("userUuid")                            // 从请求路径中获取用户的UUID
-	groupUuid := c.Param("groupUuid")                          // 从请求路径中获取组的UUID
-	err := service.GroupService.JoinGroup(groupUuid, userUuid) // 调用服务层方法，将用户加入指定的组
+	userUuid := c.Param("userUuid")   // 从请求路径中获取用户的UUID
+	groupUuid := c.Param("groupUuid") // 从请求路径中获取组的UUID
+	if userUuid != c.GetString(constant.CONTEXT_USER_UUID) {
+		c.JSON(http.StatusOK, response.FailMsg("只能以当前登录用户加入群组")) // 路径中的用户不是当前登录用户，拒绝请求
+		return
+	}
+	var joinRequest request.JoinGroupRequest // 声明一个JoinGroupRequest类型的变量，用于接收申请理由
+	c.ShouldBindJSON(&joinRequest)           // 请求体可以为空
+
+	pending, err := service.GroupService.JoinGroup(groupUuid, userUuid, joinRequest.Reason) // 调用服务层方法，按加群方式加入或申请加入指定的组
 	if err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+	if pending != nil {
+		server.PublishJoinRequestEvent(groupUuid, userUuid, userUuid, pending.Id, constant.GROUP_EVENT_REQUEST) // 通知群主和管理员审核
+		c.JSON(http.StatusOK, response.SuccessMsg(pending))                                                     // 返回待审核的申请
+		return
+	}
+	server.PublishGroupEvent(groupUuid, userUuid, userUuid, constant.GROUP_EVENT_JOIN) // 通知群成员有新成员加入
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                    // 返回成功响应
 }
 
-// GetGroupUsers 函数用于获取指定组内的所有用户信息
+// GetGroupUsers 函数用于获取指定组内的所有成员信息，包括成员的角色
 func GetGroupUsers(c *gin.Context) {
-	groupUuid := c.Param("uuid")                                  // 从请求路径中获取组的UUID
-	users := service.GroupService.GetUserIdByGroupUuid(groupUuid) // 调用服务层方法，获取组内的用户列表
-	c.JSON(http.StatusOK, response.SuccessMsg(users))             // 返回用户列表，响应成功
+	groupUuid := c.Param("uuid")                               // 从请求路径中获取组的UUID
+	members := service.GroupService.GetGroupMembers(groupUuid) // 调用服务层方法，获取组内的成员列表
+	c.JSON(http.StatusOK, response.SuccessMsg(members))        // 返回成员列表，响应成功
+}
+
+// LeaveGroup 函数用于当前用户退出群组
+func LeaveGroup(c *gin.Context) {
+	groupUuid := c.Param("uuid")                        // 从请求路径中获取组的UUID
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	if err := service.GroupService.LeaveGroup(groupUuid, userUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果退出失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, userUuid, userUuid, constant.GROUP_EVENT_LEAVE) // 通知群成员有成员退出
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                     // 返回成功响应
+}
+
+// KickGroupMember 函数用于群主或管理员将成员移出群组
+func KickGroupMember(c *gin.Context) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	memberUuid := c.Param("userUuid")                       // 从请求路径中获取被移出成员的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	if err := service.GroupService.KickMember(groupUuid, operatorUuid, memberUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果移出失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, operatorUuid, memberUuid, constant.GROUP_EVENT_KICK) // 被移出的成员同样会收到通知
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                          // 返回成功响应
+}
+
+// PromoteGroupMember 函数用于群主将成员设为管理员
+func PromoteGroupMember(c *gin.Context) {
+	setGroupMemberRole(c, constant.GROUP_ROLE_ADMIN, constant.GROUP_EVENT_PROMOTE)
+}
+
+// DemoteGroupMember 函数用于群主取消成员的管理员
+func DemoteGroupMember(c *gin.Context) {
+	setGroupMemberRole(c, constant.GROUP_ROLE_MEMBER, constant.GROUP_EVENT_DEMOTE)
+}
+
+// setGroupMemberRole 函数修改路径中指定成员的角色，成功后发布对应的群组系统消息
+func setGroupMemberRole(c *gin.Context, role int16, event string) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	memberUuid := c.Param("userUuid")                       // 从请求路径中获取成员的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	if err := service.GroupService.SetMemberRole(groupUuid, operatorUuid, memberUuid, role); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果修改角色失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, operatorUuid, memberUuid, event) // 通知群成员角色变化
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                      // 返回成功响应
+}
+
+// TransferGroupOwner 函数用于群主将群组转让给其他成员
+func TransferGroupOwner(c *gin.Context) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	memberUuid := c.Param("userUuid")                       // 从请求路径中获取新群主的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前群主的UUID
+	if err := service.GroupService.TransferOwner(groupUuid, operatorUuid, memberUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果转让失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, operatorUuid, memberUuid, constant.GROUP_EVENT_TRANSFER) // 通知群成员群主变化
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                              // 返回成功响应
+}
+
+// DissolveGroup 函数用于群主解散群组，解散后逐个通知原来的成员
+func DissolveGroup(c *gin.Context) {
+	groupUuid := c.Param("uuid")                                                // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID)                     // 从上下文中获取群主的UUID
+	members, err := service.GroupService.DissolveGroup(groupUuid, operatorUuid) // 调用服务层方法解散群组，返回原来的成员
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果解散失败，返回错误信息
+		return
+	}
+	for _, member := range members { // 群组已没有成员，系统消息通过target发送给每个原来的成员
+		server.PublishGroupEvent(groupUuid, operatorUuid, member, constant.GROUP_EVENT_DISSOLVE)
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
+
+// MuteGroupMember 函数用于群主或管理员禁言成员，请求体中的duration为禁言时长（秒），不传或为0表示永久禁言
+func MuteGroupMember(c *gin.Context) {
+	var muteRequest request.GroupMuteRequest // 声明一个GroupMuteRequest类型的变量，用于接收禁言时长
+	c.ShouldBindJSON(&muteRequest)           // 请求体可以为空
+
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	memberUuid := c.Param("userUuid")                       // 从请求路径中获取被禁言成员的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	if err := service.GroupService.MuteMember(groupUuid, operatorUuid, memberUuid, muteRequest.Duration); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果禁言失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, operatorUuid, memberUuid, constant.GROUP_EVENT_MUTE) // 通知群成员有成员被禁言
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                          // 返回成功响应
+}
+
+// UnmuteGroupMember 函数用于群主或管理员解除成员的禁言
+func UnmuteGroupMember(c *gin.Context) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	memberUuid := c.Param("userUuid")                       // 从请求路径中获取被解除禁言成员的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	if err := service.GroupService.UnmuteMember(groupUuid, operatorUuid, memberUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果解除禁言失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, operatorUuid, memberUuid, constant.GROUP_EVENT_UNMUTE) // 通知群成员有成员被解除禁言
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                            // 返回成功响应
+}
+
+// MuteAllGroupMembers 函数用于群主或管理员开启全员禁言
+func MuteAllGroupMembers(c *gin.Context) {
+	setGroupMuteAll(c, true, constant.GROUP_EVENT_MUTE_ALL)
+}
+
+// UnmuteAllGroupMembers 函数用于群主或管理员关闭全员禁言
+func UnmuteAllGroupMembers(c *gin.Context) {
+	setGroupMuteAll(c, false, constant.GROUP_EVENT_UNMUTE_ALL)
+}
+
+// setGroupMuteAll 函数开启或关闭全员禁言，成功后发布对应的群组系统消息
+func setGroupMuteAll(c *gin.Context, muteAll bool, event string) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	if err := service.GroupService.SetMuteAll(groupUuid, operatorUuid, muteAll); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果修改失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, operatorUuid, "", event) // 通知群成员全员禁言状态变化
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))              // 返回成功响应
+}
+
+// SetGroupJoinPolicy 函数用于群主或管理员修改群组的加群方式
+func SetGroupJoinPolicy(c *gin.Context) {
+	var policyRequest request.GroupPolicyRequest // 声明一个GroupPolicyRequest类型的变量，用于接收加群方式
+	c.ShouldBindJSON(&policyRequest)             // 将请求中的JSON数据绑定到policyRequest变量
+
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	if err := service.GroupService.SetJoinPolicy(groupUuid, operatorUuid, policyRequest.JoinPolicy); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果修改失败，返回错误信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
+
+// CreateGroupInvite 函数用于群主或管理员创建邀请链接，请求体中的expire为有效时长（秒），maxUses为最多使用次数，都为0表示不限制
+func CreateGroupInvite(c *gin.Context) {
+	var inviteRequest request.GroupInviteRequest // 声明一个GroupInviteRequest类型的变量，用于接收邀请链接的参数
+	c.ShouldBindJSON(&inviteRequest)             // 请求体可以为空
+
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	invite, err := service.GroupService.CreateInvite(groupUuid, operatorUuid, inviteRequest.Expire, inviteRequest.MaxUses)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果创建失败，返回错误信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(invite)) // 返回创建的邀请链接
+}
+
+// GetGroupInvites 函数用于群主或管理员查看仍然可以使用的邀请链接
+func GetGroupInvites(c *gin.Context) {
+	groupUuid := c.Param("uuid")                                             // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID)                  // 从上下文中获取操作者的UUID
+	invites, err := service.GroupService.GetInvites(groupUuid, operatorUuid) // 调用服务层方法获取可用的邀请链接
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(invites)) // 返回邀请链接列表
+}
+
+// RevokeGroupInvite 函数用于群主或管理员撤销邀请链接
+func RevokeGroupInvite(c *gin.Context) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	code := c.Param("code")                                 // 从请求路径中获取邀请码
+	if err := service.GroupService.RevokeInvite(groupUuid, operatorUuid, code); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果撤销失败，返回错误信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
+
+// JoinGroupByInvite 函数用于当前用户通过邀请码加入群组，不需要审核
+func JoinGroupByInvite(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)                            // 从上下文中获取当前用户的UUID
+	groupUuid, err := service.GroupService.JoinByInvite(c.Param("code"), userUuid) // 使用路径中的邀请码加入群组
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果加入失败，返回错误信息
+		return
+	}
+	server.PublishGroupEvent(groupUuid, userUuid, userUuid, constant.GROUP_EVENT_JOIN) // 通知群成员有新成员加入
+	c.JSON(http.StatusOK, response.SuccessMsg(groupUuid))                              // 返回加入的群组UUID
+}
+
+// GetGroupJoinRequests 函数用于群主或管理员查看待审核的加群申请
+func GetGroupJoinRequests(c *gin.Context) {
+	groupUuid := c.Param("uuid")                                                   // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID)                        // 从上下文中获取操作者的UUID
+	requests, err := service.GroupService.GetJoinRequests(groupUuid, operatorUuid) // 调用服务层方法获取待审核的加群申请
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(requests)) // 返回加群申请列表
+}
+
+// ApproveGroupJoinRequest 函数用于群主或管理员通过加群申请，申请人加入群组后通知群组所有成员
+func ApproveGroupJoinRequest(c *gin.Context) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	requestId, err := strconv.Atoi(c.Param("id"))           // 从请求路径中获取加群申请的id
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("加群申请不存在")) // id不是数字时申请不存在
+		return
+	}
+	applicantUuid, joined, err := service.GroupService.ApproveJoinRequest(groupUuid, operatorUuid, int32(requestId))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果处理失败，返回错误信息
+		return
+	}
+	if joined { // 申请人已通过邀请链接加入时已经发布过加入通知
+		server.PublishJoinRequestEvent(groupUuid, operatorUuid, applicantUuid, int32(requestId), constant.GROUP_EVENT_JOIN)
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
+
+// RejectGroupJoinRequest 函数用于群主或管理员拒绝加群申请，通知群主、管理员和申请人
+func RejectGroupJoinRequest(c *gin.Context) {
+	groupUuid := c.Param("uuid")                            // 从请求路径中获取组的UUID
+	operatorUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取操作者的UUID
+	requestId, err := strconv.Atoi(c.Param("id"))           // 从请求路径中获取加群申请的id
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("加群申请不存在")) // id不是数字时申请不存在
+		return
+	}
+	applicantUuid, err := service.GroupService.RejectJoinRequest(groupUuid, operatorUuid, int32(requestId))
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果处理失败，返回错误信息
+		return
+	}
+	server.PublishJoinRequestEvent(groupUuid, operatorUuid, applicantUuid, int32(requestId), constant.GROUP_EVENT_REJECT) // 通知群主、管理员和申请人
+	c.JSON(http.StatusOK, response.SuccessMsg(nil))                                                                       // 返回成功响应
 }
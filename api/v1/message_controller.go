@@ -2,8 +2,11 @@ package v1
 
 import (
 	"net/http" // 提供HTTP客户端和服务端的功能
+	"strconv"  // 引入字符串转换包，用于解析消息id
 
+	"chat-room/internal/server"     // 引入服务器包，用于发布消息撤回和编辑通知
 	"chat-room/internal/service"    // 引入服务层，用于调用业务逻辑
+	"chat-room/pkg/common/constant" // 引入常量包，定义了上下文键名
 	"chat-room/pkg/common/request"  // 引入通用请求包，定义了请求参数结构体
 	"chat-room/pkg/common/response" // 引入通用响应包，用于统一格式化HTTP响应
 	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
@@ -11,7 +14,7 @@ import (
 	"github.com/gin-gonic/gin" // 引入Gin框架，用于处理HTTP请求
 )
 
-// GetMessage 函数用于获取某个用户的消息列表
+// GetMessage 函数用于获取当前登录用户与好友或所在群组的消息列表
 func GetMessage(c *gin.Context) {
 	log.Logger.Info(c.Query("uuid"))          // 记录请求中的UUID参数到日志中
 	var messageRequest request.MessageRequest // 声明一个MessageRequest类型的变量，用于接收请求参数
@@ -21,7 +24,8 @@ func GetMessage(c *gin.Context) {
 	}
 	log.Logger.Info("messageRequest params: ", log.Any("messageRequest", messageRequest)) // 记录绑定后的请求参数到日志中
 
-	messages, err := service.MessageService.GetMessages(messageRequest) // 调用服务层方法，获取用户的消息列表
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)                           // 获取当前登录用户的uuid，单聊消息只能查询当前用户参与的会话
+	messages, err := service.MessageService.GetMessages(userUuid, messageRequest) // 调用服务层方法，获取用户的消息列表
 	if err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
 		return
@@ -29,3 +33,83 @@ func GetMessage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response.SuccessMsg(messages)) // 返回消息列表，响应成功
 }
+
+// SearchMessage 函数在当前登录用户参与的会话中检索消息
+func SearchMessage(c *gin.Context) {
+	var searchRequest request.MessageSearchRequest // 声明一个MessageSearchRequest类型的变量，用于接收请求参数
+	c.ShouldBindQuery(&searchRequest)              // 将查询参数绑定到searchRequest变量
+
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)                            // 获取当前登录用户的uuid
+	results, err := service.MessageService.SearchMessages(userUuid, searchRequest) // 调用服务层方法检索消息
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(results)) // 返回检索结果，响应成功
+}
+
+// GetConversations 函数获取当前登录用户的会话列表，包含每个会话的最后一条消息和未读数
+func GetConversations(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)                     // 获取当前登录用户的uuid
+	conversations, err := service.MessageService.GetConversations(userUuid) // 调用服务层方法获取会话列表
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(conversations)) // 返回会话列表，响应成功
+}
+
+// EditMessage 函数用于发送者编辑自己发送的文字消息，编辑成功后通知会话的双方或群组成员
+func EditMessage(c *gin.Context) {
+	var editRequest request.EditMessageRequest // 声明一个EditMessageRequest类型的变量，用于接收编辑后的内容
+	c.ShouldBindJSON(&editRequest)             // 将请求中的JSON数据绑定到editRequest变量
+
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 获取当前登录用户的uuid
+	messageId, err := strconv.Atoi(c.Param("id"))       // 从请求路径中获取消息id
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息不存在")) // id不是数字时消息不存在
+		return
+	}
+	event, err := service.MessageService.EditMessage(userUuid, int32(messageId), editRequest.Content) // 调用服务层方法编辑消息，返回编辑通知
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果编辑失败，返回错误信息
+		return
+	}
+	server.PublishMessageEvent(event)               // 通知会话的双方或群组成员
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
+
+// RecallMessage 函数用于撤回消息，撤回成功后通知会话的双方或群组成员
+func RecallMessage(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 获取当前登录用户的uuid
+	messageId, err := strconv.Atoi(c.Param("id"))       // 从请求路径中获取消息id
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息不存在")) // id不是数字时消息不存在
+		return
+	}
+	event, err := service.MessageService.RecallMessage(userUuid, int32(messageId)) // 调用服务层方法撤回消息，返回撤回通知
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果撤回失败，返回错误信息
+		return
+	}
+	server.PublishMessageEvent(event)               // 通知会话的双方或群组成员
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
+
+// GetMessageHistory 函数用于获取消息的编辑历史
+func GetMessageHistory(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 获取当前登录用户的uuid
+	messageId, err := strconv.Atoi(c.Param("id"))       // 从请求路径中获取消息id
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg("消息不存在")) // id不是数字时消息不存在
+		return
+	}
+	edits, err := service.MessageService.GetMessageHistory(userUuid, int32(messageId)) // 调用服务层方法获取编辑历史
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果出现错误，返回失败信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(edits)) // 返回编辑历史，响应成功
+}
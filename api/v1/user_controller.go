@@ -4,7 +4,9 @@ import (
 	"net/http" // 提供HTTP客户端和服务端的功能
 
 	"chat-room/internal/model"      // 引入数据模型包，定义了数据库表结构
+	"chat-room/internal/server"     // 引入服务器包，用于查询用户在线状态和在线设备
 	"chat-room/internal/service"    // 引入服务层，用于调用业务逻辑
+	"chat-room/pkg/common/constant" // 引入常量包，定义了上下文键名
 	"chat-room/pkg/common/request"  // 引入通用请求包，定义了请求参数结构体
 	"chat-room/pkg/common/response" // 引入通用响应包，用于统一格式化HTTP响应
 	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
@@ -12,19 +14,35 @@ import (
 	"github.com/gin-gonic/gin" // 引入Gin框架，用于处理HTTP请求
 )
 
-// Login 函数用于处理用户登录请求
+// Login 函数用于处理用户登录请求，登录成功后返回绑定设备的access token和refresh token
+// 设备id通过deviceId查询参数指定，由客户端生成并持久保存，未指定时由服务端生成并在响应中返回
 func Login(c *gin.Context) {
 	var user model.User // 声明一个User类型的变量，用于接收客户端发送的登录信息
 	// c.BindJSON(&user)  // 解析请求中的JSON数据，绑定到user变量
-	c.ShouldBindJSON(&user)                         // 使用ShouldBindJSON方法绑定请求中的JSON数据到user变量
-	log.Logger.Debug("user", log.Any("user", user)) // 记录用户登录信息到日志中
+	c.ShouldBindJSON(&user)                                         // 使用ShouldBindJSON方法绑定请求中的JSON数据到user变量
+	log.Logger.Debug("user", log.String("username", user.Username)) // 记录登录的用户名到日志中，不记录密码
 
-	if service.UserService.Login(&user) { // 调用服务层的Login方法验证用户信息
-		c.JSON(http.StatusOK, response.SuccessMsg(user)) // 登录成功，返回用户信息
+	loginResponse, err := service.UserService.Login(&user, c.Query("deviceId")) // 调用服务层的Login方法验证用户信息并为设备签发token
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 登录失败，返回失败信息
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(loginResponse)) // 登录成功，返回用户信息和token
+}
+
+// RefreshToken 函数使用refresh token换取一组新的token
+func RefreshToken(c *gin.Context) {
+	var refreshTokenRequest request.RefreshTokenRequest // 声明一个RefreshTokenRequest类型的变量，用于接收请求参数
+	c.ShouldBindJSON(&refreshTokenRequest)              // 将请求中的JSON数据绑定到refreshTokenRequest变量
+
+	loginResponse, err := service.UserService.RefreshToken(refreshTokenRequest.RefreshToken) // 调用服务层方法重新签发token
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果刷新失败，返回错误信息
 		return
 	}
 
-	c.JSON(http.StatusOK, response.FailMsg("Login failed")) // 登录失败，返回失败信息
+	c.JSON(http.StatusOK, response.SuccessMsg(loginResponse)) // 刷新成功，返回新的token
 }
 
 // Register 函数用于处理用户注册请求
@@ -36,16 +54,18 @@ func Register(c *gin.Context) {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果注册失败，返回错误信息
 		return
 	}
+	user.Password = "" // 不返回密码哈希
 
 	c.JSON(http.StatusOK, response.SuccessMsg(user)) // 注册成功，返回用户信息
 }
 
 // ModifyUserInfo 函数用于处理用户信息修改请求
 func ModifyUserInfo(c *gin.Context) {
-	var user model.User                             // 声明一个User类型的变量，用于接收客户端发送的修改信息
-	c.ShouldBindJSON(&user)                         // 将请求中的JSON数据绑定到user变量
-	log.Logger.Debug("user", log.Any("user", user)) // 记录用户信息到日志中
-	if err := service.UserService.ModifyUserInfo(&user); err != nil {
+	var user model.User                                             // 声明一个User类型的变量，用于接收客户端发送的修改信息
+	c.ShouldBindJSON(&user)                                         // 将请求中的JSON数据绑定到user变量
+	log.Logger.Debug("user", log.String("nickname", user.Nickname)) // 记录修改的昵称到日志中，不记录密码
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)             // 只能修改当前登录用户的信息，忽略请求体中的用户名
+	if err := service.UserService.ModifyUserInfo(userUuid, &user); err != nil {
 		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果修改失败，返回错误信息
 		return
 	}
@@ -60,29 +80,79 @@ func GetUserDetails(c *gin.Context) {
 	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetUserDetails(uuid))) // 返回用户详细信息
 }
 
+// GetUserPresence 函数获取指定用户的在线状态和最后在线时间，供无法使用WebSocket的客户端轮询
+func GetUserPresence(c *gin.Context) {
+	uuid := c.Param("uuid") // 从请求路径中获取用户的UUID
+	if !service.UserService.CanViewPresence(c.GetString(constant.CONTEXT_USER_UUID), uuid) {
+		c.JSON(http.StatusOK, response.FailMsg("只能查看好友的在线状态")) // 不是好友或已被对方屏蔽，不返回在线状态
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessMsg(server.MyServer.GetPresence(uuid))) // 返回用户在线状态
+}
+
+// GetUserSessions 函数获取当前用户在线的所有设备
+func GetUserSessions(c *gin.Context) {
+	uuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+
+	sessions, err := server.MyServer.GetSessions(uuid)
+	if err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 查询失败，返回错误信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(sessions)) // 返回在线设备列表
+}
+
+// DeleteUserSession 函数将当前用户的指定设备远程下线，该设备的token同时失效
+func DeleteUserSession(c *gin.Context) {
+	uuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	deviceId := c.Param("deviceId")                 // 从请求路径中获取设备id
+
+	if err := server.MyServer.KickSession(uuid, deviceId); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 设备不存在，返回错误信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 下线成功，返回成功信息
+}
+
 // GetUserOrGroupByName 函数通过用户名或组名获取用户或组信息
 func GetUserOrGroupByName(c *gin.Context) {
 	name := c.Query("name") // 从查询参数中获取名称
 
-	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetUserOrGroupByName(name))) // 返回匹配的用户或组信息
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)                                                  // 获取当前登录用户的uuid，屏蔽了当前用户的用户不会出现在结果中
+	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetUserOrGroupByName(userUuid, name))) // 返回匹配的用户或组信息
 }
 
-// GetUserList 函数用于获取用户的好友列表
+// GetUserList 函数用于获取当前登录用户的好友列表
 func GetUserList(c *gin.Context) {
-	uuid := c.Query("uuid")                                                           // 从查询参数中获取用户的UUID
+	uuid := c.GetString(constant.CONTEXT_USER_UUID)                                   // 只能获取当前登录用户的好友列表
 	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetUserList(uuid))) // 返回用户好友列表
 }
 
-// AddFriend 函数用于处理添加好友请求
-func AddFriend(c *gin.Context) {
-	var userFriendRequest request.FriendRequest // 声明一个FriendRequest类型的变量，用于接收客户端发送的好友请求信息
-	c.ShouldBindJSON(&userFriendRequest)        // 将请求中的JSON数据绑定到userFriendRequest变量
+// GetBlockedUsers 函数用于获取当前用户的黑名单
+func GetBlockedUsers(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID)                                       // 从上下文中获取当前用户的UUID
+	c.JSON(http.StatusOK, response.SuccessMsg(service.UserService.GetBlockedUsers(userUuid))) // 返回黑名单中的用户
+}
 
-	err := service.UserService.AddFriend(&userFriendRequest) // 调用服务层的AddFriend方法添加好友
-	if nil != err {
-		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果添加失败，返回错误信息
+// BlockUser 函数用于将路径中指定的用户加入当前用户的黑名单
+func BlockUser(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	blockedUuid := c.Param("uuid")                      // 从请求路径中获取被屏蔽用户的UUID
+	if err := service.UserService.BlockUser(userUuid, blockedUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果屏蔽失败，返回错误信息
 		return
 	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
+}
 
-	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 添加成功，返回成功信息
+// UnblockUser 函数用于将路径中指定的用户移出当前用户的黑名单
+func UnblockUser(c *gin.Context) {
+	userUuid := c.GetString(constant.CONTEXT_USER_UUID) // 从上下文中获取当前用户的UUID
+	blockedUuid := c.Param("uuid")                      // 从请求路径中获取被解除屏蔽用户的UUID
+	if err := service.UserService.UnblockUser(userUuid, blockedUuid); err != nil {
+		c.JSON(http.StatusOK, response.FailMsg(err.Error())) // 如果解除屏蔽失败，返回错误信息
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessMsg(nil)) // 返回成功响应
 }
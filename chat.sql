@@ -46,13 +46,57 @@ CREATE TABLE IF NOT EXISTS `messages` (
   `pic` text COMMENT '缩略图',
   `message_type` smallint DEFAULT NULL COMMENT '''消息类型：1单聊，2群聊''',
   `content_type` smallint DEFAULT NULL COMMENT '''消息内容类型：1文字，2语音，3视频''',
+  `edited_at` bigint DEFAULT 0 COMMENT '''最后一次编辑的时间戳（毫秒），0表示没有编辑过''',
+  `recalled_at` bigint DEFAULT 0 COMMENT '''撤回的时间戳（毫秒），0表示没有撤回''',
   PRIMARY KEY (`id`),
   KEY `idx_messages_deleted_at` (`deleted_at`),
   KEY `idx_messages_from_user_id` (`from_user_id`),
-  KEY `idx_messages_to_user_id` (`to_user_id`)
+  KEY `idx_messages_to_user_id` (`to_user_id`),
+  KEY `idx_messages_conversation` (`to_user_id`, `message_type`, `id`),
+  FULLTEXT KEY `idx_messages_content` (`content`) WITH PARSER ngram
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '消息表';
 
 
+DROP TABLE IF EXISTS `message_edits`;
+CREATE TABLE IF NOT EXISTS `message_edits` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL COMMENT '编辑时间',
+  `message_id` int DEFAULT NULL COMMENT '''消息ID''',
+  `content` varchar(2500) DEFAULT NULL COMMENT '''编辑前的内容''',
+  PRIMARY KEY (`id`),
+  KEY `idx_message_edits_message_id` (`message_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '消息编辑历史表';
+
+
+DROP TABLE IF EXISTS `user_blocks`;
+CREATE TABLE IF NOT EXISTS `user_blocks` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `deleted_at` bigint unsigned DEFAULT NULL,
+  `user_id` int DEFAULT NULL COMMENT '''用户ID''',
+  `blocked_id` int DEFAULT NULL COMMENT '''被屏蔽用户ID''',
+  PRIMARY KEY (`id`),
+  KEY `idx_user_blocks_user_id` (`user_id`),
+  KEY `idx_user_blocks_blocked_id` (`blocked_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '用户黑名单表';
+
+
+DROP TABLE IF EXISTS `friend_requests`;
+CREATE TABLE IF NOT EXISTS `friend_requests` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `from_id` int DEFAULT NULL COMMENT '''发送者ID''',
+  `to_id` int DEFAULT NULL COMMENT '''接收者ID''',
+  `reason` varchar(350) DEFAULT NULL COMMENT '''验证消息''',
+  `status` smallint DEFAULT 0 COMMENT '''状态：0待处理，1已通过，2已拒绝，3已取消''',
+  PRIMARY KEY (`id`),
+  KEY `idx_friend_requests_from_id` (`from_id`),
+  KEY `idx_friend_requests_to_id` (`to_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '好友请求表';
+
+
 DROP TABLE IF EXISTS `groups`;
 CREATE TABLE IF NOT EXISTS  `groups` (
   `id` int NOT NULL AUTO_INCREMENT,
@@ -62,6 +106,8 @@ CREATE TABLE IF NOT EXISTS  `groups` (
   `user_id` int DEFAULT NULL COMMENT '''群主ID''',
   `name` varchar(150) DEFAULT NULL COMMENT '''群名称',
   `notice` varchar(350) DEFAULT NULL COMMENT '''群公告',
+  `mute_all` smallint DEFAULT 0 COMMENT '''全员禁言：0关闭 1开启，群主和管理员不受限制''',
+  `join_policy` smallint DEFAULT 0 COMMENT '''加群方式：0直接加入 1需要审核 2只能通过邀请链接加入''',
   `uuid` varchar(150) NOT NULL COMMENT '''uuid''',
   PRIMARY KEY (`id`),
   KEY `idx_groups_user_id` (`user_id`)
@@ -78,7 +124,85 @@ CREATE TABLE  IF NOT EXISTS `group_members` (
   `group_id` int DEFAULT NULL COMMENT '''群组ID''',
   `nickname` varchar(350) DEFAULT NULL COMMENT '''昵称',
   `mute` smallint DEFAULT NULL COMMENT '''是否禁言''',
+  `mute_until` bigint DEFAULT 0 COMMENT '''禁言截止时间（毫秒），0表示永久禁言''',
+  `role` smallint DEFAULT 0 COMMENT '''角色：0普通成员 1管理员 2群主''',
   PRIMARY KEY (`id`),
   KEY `idx_group_members_user_id` (`user_id`),
   KEY `idx_group_members_group_id` (`group_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '群组成员表';
+
+
+DROP TABLE IF EXISTS `group_invites`;
+CREATE TABLE IF NOT EXISTS `group_invites` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `deleted_at` bigint unsigned DEFAULT NULL,
+  `code` varchar(64) NOT NULL COMMENT '''邀请码''',
+  `group_id` int DEFAULT NULL COMMENT '''群组ID''',
+  `user_id` int DEFAULT NULL COMMENT '''创建者ID''',
+  `expires_at` bigint DEFAULT 0 COMMENT '''过期时间（毫秒），0表示永不过期''',
+  `max_uses` int DEFAULT 0 COMMENT '''最大使用次数，0表示不限次数''',
+  `uses` int DEFAULT 0 COMMENT '''已使用次数''',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_group_invites_code` (`code`),
+  KEY `idx_group_invites_group_id` (`group_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '群组邀请链接表';
+
+
+DROP TABLE IF EXISTS `group_join_requests`;
+CREATE TABLE IF NOT EXISTS `group_join_requests` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `group_id` int DEFAULT NULL COMMENT '''群组ID''',
+  `user_id` int DEFAULT NULL COMMENT '''申请人ID''',
+  `reason` varchar(350) DEFAULT NULL COMMENT '''申请理由''',
+  `status` smallint DEFAULT 0 COMMENT '''状态：0待审核，1已通过，2已拒绝''',
+  `operator_id` int DEFAULT 0 COMMENT '''处理人ID''',
+  PRIMARY KEY (`id`),
+  KEY `idx_group_join_requests_group_id` (`group_id`),
+  KEY `idx_group_join_requests_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '加群申请表';
+
+
+DROP TABLE IF EXISTS `message_cursors`;
+CREATE TABLE IF NOT EXISTS `message_cursors` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `user_id` int DEFAULT NULL COMMENT '''用户ID''',
+  `device_id` varchar(150) DEFAULT '' COMMENT '''设备ID，为空时为已读位置''',
+  `message_type` smallint DEFAULT NULL COMMENT '''会话类型：0全局起点，1单聊，2群聊''',
+  `conversation_id` int DEFAULT NULL COMMENT '''会话ID：单聊为对方用户ID，群聊为群组ID''',
+  `last_message_id` int DEFAULT NULL COMMENT '''已投递的最后一条消息ID''',
+  `last_read_id` int DEFAULT NULL COMMENT '''已读的最后一条消息ID''',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_cursor_device` (`user_id`, `device_id`, `message_type`, `conversation_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '消息投递游标表';
+
+DROP TABLE IF EXISTS `user_nodes`;
+CREATE TABLE IF NOT EXISTS `user_nodes` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `user_uuid` varchar(150) DEFAULT NULL COMMENT '''用户uuid''',
+  `device_id` varchar(150) DEFAULT NULL COMMENT '''设备id''',
+  `device_type` varchar(20) DEFAULT NULL COMMENT '''设备类型''',
+  `remote_addr` varchar(150) DEFAULT NULL COMMENT '''远端地址''',
+  `node_id` varchar(150) DEFAULT NULL COMMENT '''节点id''',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_device` (`user_uuid`, `device_id`),
+  KEY `idx_user_nodes_node_id` (`node_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '用户连接路由表';
+
+DROP TABLE IF EXISTS `device_tokens`;
+CREATE TABLE IF NOT EXISTS `device_tokens` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) DEFAULT NULL,
+  `updated_at` datetime(3) DEFAULT NULL,
+  `user_uuid` varchar(150) DEFAULT NULL COMMENT '''用户uuid''',
+  `device_id` varchar(150) DEFAULT NULL COMMENT '''设备id''',
+  `version` int DEFAULT '0' COMMENT '''token版本''',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_device_token` (`user_uuid`, `device_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT '设备token版本表';
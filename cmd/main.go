@@ -1,14 +1,17 @@
 package main
 
 import (
-	"chat-room/config"              // 引入配置包，用于加载和访问配置信息
-	"chat-room/internal/kafka"      // 引入Kafka包，用于处理Kafka消息队列
-	"chat-room/internal/router"     // 引入路由包，用于定义HTTP请求路由
-	"chat-room/internal/server"     // 引入服务器包，用于管理WebSocket服务器
-	"chat-room/pkg/common/constant" // 引入常量包，定义了项目中使用的常量
-	"chat-room/pkg/global/log"      // 引入日志包，用于日志记录
-	"net/http"                      // 提供HTTP服务器的功能
-	"time"                          // 提供时间相关的功能
+	"chat-room/config"           // 引入配置包，用于加载和访问配置信息
+	"chat-room/internal/router"  // 引入路由包，用于定义HTTP请求路由
+	"chat-room/internal/server"  // 引入服务器包，用于管理WebSocket服务器
+	"chat-room/internal/service" // 引入服务层，用于初始化消息检索索引
+	"chat-room/pkg/global/log"   // 引入日志包，用于日志记录
+	"context"                    // 提供上下文，用于控制关闭服务的期限
+	"net/http"                   // 提供HTTP服务器的功能
+	"os"                         // 提供操作系统信号类型
+	"os/signal"                  // 提供信号监听的功能
+	"syscall"                    // 提供SIGTERM等信号常量
+	"time"                       // 提供时间相关的功能
 )
 
 func main() {
@@ -17,25 +20,24 @@ func main() {
 	// 记录当前的配置信息
 	log.Logger.Info("config", log.Any("config", config.GetConfig()))
 
-	// 如果消息通道类型是Kafka，初始化Kafka生产者和消费者
-	if config.GetConfig().MsgChannelType.ChannelType == constant.KAFKA {
-		// 初始化Kafka生产者，指定Kafka主题和主机地址
-		kafka.InitProducer(config.GetConfig().MsgChannelType.KafkaTopic, config.GetConfig().MsgChannelType.KafkaHosts)
-		// 初始化Kafka消费者，指定主机地址
-		kafka.InitConsumer(config.GetConfig().MsgChannelType.KafkaHosts)
-		// 启动一个goroutine来异步消费Kafka消息，并处理消息
-		go kafka.ConsumerMsg(server.ConsumerKafkaMsg)
+	// 根据配置初始化群成员和用户资料缓存，消息总线收到的消息路由时会使用缓存
+	service.InitCache()
+
+	// 根据配置创建消息总线（gochannel、kafka或redis），并订阅需要本节点处理的消息
+	if err := server.InitMessageBus(); err != nil {
+		log.Logger.Error("init message bus error", log.String("init message bus error", err.Error()))
+		return
 	}
 
+	// 根据配置初始化消息全文检索索引
+	service.MessageService.InitSearchIndex()
+
 	// 记录服务器启动信息
 	log.Logger.Info("start server", log.String("start", "start web server..."))
 
 	// 初始化路由
 	newRouter := router.NewRouter()
 
-	// 启动WebSocket服务器
-	go server.MyServer.Start()
-
 	// 配置并启动HTTP服务器
 	s := &http.Server{
 		Addr:           "0.0.0.0:8888",   // 监听所有网络接口上的8888端口
@@ -45,9 +47,41 @@ func main() {
 		MaxHeaderBytes: 1 << 20,          // 设置请求头的最大字节数为1MB
 	}
 	// 启动HTTP服务器并监听端口
-	err := s.ListenAndServe()
-	if nil != err {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- s.ListenAndServe()
+	}()
+
+	// 等待退出信号，部署时收到SIGTERM后优雅关闭
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
 		// 如果服务器启动失败，记录错误信息
 		log.Logger.Error("server error", log.Any("serverError", err))
+		return
+	case sig := <-quit:
+		log.Logger.Info("shutdown server", log.String("signal", sig.String()))
+	}
+
+	shutdown(s)
+}
+
+// shutdown 函数在配置的期限内优雅关闭服务：停止接受新的请求和WebSocket连接，通知客户端重连并等待其注销，
+// 等待在途的消息保存完成，最后关闭消息总线，刷新Kafka生产者中缓冲的消息
+func shutdown(s *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout())
+	defer cancel()
+
+	// 先停止WebSocket升级，再关闭HTTP监听，已升级的连接不受http.Server.Shutdown影响
+	if err := server.MyServer.Shutdown(ctx); err != nil {
+		log.Logger.Error("shutdown websocket error", log.String("shutdown websocket error", err.Error()))
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		log.Logger.Error("shutdown http server error", log.String("shutdown http server error", err.Error()))
+	}
+	if err := server.CloseMessageBus(); err != nil {
+		log.Logger.Error("close message bus error", log.String("close message bus error", err.Error()))
 	}
+	log.Logger.Info("shutdown server", log.String("shutdown", "server exited"))
 }
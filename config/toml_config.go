@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt" // 引入格式化输入输出的标准库
+	"os"  // 引入操作系统包，用于获取主机名
 
 	"github.com/spf13/viper" // 引入Viper库，用于配置管理
 )
@@ -13,6 +14,12 @@ type TomlConfig struct {
 	Log            LogConfig      // 日志配置
 	StaticPath     PathConfig     // 静态文件路径配置
 	MsgChannelType MsgChannelType // 消息队列类型及相关配置
+	Auth           AuthConfig     // 登录认证（token签发）配置
+	Search         SearchConfig   // 消息全文检索配置
+	Cache          CacheConfig    // 群成员和用户资料缓存配置
+	Message        MessageConfig  // 消息撤回和编辑配置
+	Redis          RedisConfig    // Redis连接配置
+	Server         ServerConfig   // HTTP和WebSocket服务配置
 }
 
 // MySQLConfig 结构体表示MySQL相关配置
@@ -37,11 +44,63 @@ type PathConfig struct {
 }
 
 // MsgChannelType 结构体表示消息队列类型及其相关配置信息
-// 如果使用Go的channel，则为单机使用；如果使用Kafka，则支持分布式扩展
+// 如果使用Go的channel，则为单机使用；如果使用Kafka或Redis，则支持分布式扩展
 type MsgChannelType struct {
-	ChannelType string // 消息通道类型（Gochannel或Kafka）
-	KafkaHosts  string // Kafka的主机地址列表
-	KafkaTopic  string // Kafka的主题
+	ChannelType      string // 消息通道类型（gochannel、kafka或redis）
+	KafkaHosts       string // Kafka的主机地址列表
+	KafkaTopic       string // Kafka的主题，所有节点共同消费，按会话路由到用户所在的节点
+	KafkaGroup       string // Kafka消费者组名称前缀，节点主题的消费者组为"前缀.节点id"
+	KafkaPartitions  int32  // 路由主题的分区数，决定最多可以有多少个节点并行路由消息
+	KafkaAcks        string // 生产者确认级别：all、leader或none
+	KafkaRetries     int    // 生产者发送失败时的重试次数
+	KafkaIdempotent  bool   // 是否开启幂等生产，要求kafkaAcks为all
+	KafkaCompression string // 生产者压缩算法：none、gzip、snappy、lz4或zstd
+	NodeId           string // 当前节点的id，每个实例必须唯一，为空时使用主机名
+	RedisChannel     string // Redis模式下所有节点共同订阅的频道
+}
+
+// ServerConfig 结构体表示HTTP和WebSocket服务配置
+type ServerConfig struct {
+	ShutdownTimeout    int    // 关闭服务时等待连接断开和消息保存完成的最长时间，单位为秒，不大于0时为15秒
+	SendQueueSize      int    // 每个WebSocket连接的发送队列长度
+	WriteTimeout       int    // 向WebSocket连接写入一条消息的超时时间，单位为秒
+	SlowConsumerPolicy string // 发送队列写满时的处理策略：drop_oldest、disconnect或offline
+	PingInterval       int    // 服务端发送WebSocket ping控制帧的间隔，单位为秒
+	PongTimeout        int    // 读超时，超过该时间没有收到pong或任何消息时断开连接，单位为秒，应大于pingInterval
+	HubShards          int    // 路由消息的worker数和连接注册表的分片数，为0时使用CPU核数
+}
+
+// RedisConfig 结构体表示Redis连接配置
+type RedisConfig struct {
+	Addr     string // Redis地址，格式为host:port
+	Password string // Redis密码
+	DB       int    // Redis数据库编号
+}
+
+// AuthConfig 结构体表示token签发相关配置
+type AuthConfig struct {
+	Secret             string // 签名密钥，用于HMAC签名和校验token
+	AccessTokenExpire  int    // access token的有效期，单位为分钟
+	RefreshTokenExpire int    // refresh token的有效期，单位为分钟
+}
+
+// SearchConfig 结构体表示消息全文检索配置
+// mysql使用messages表上的FULLTEXT索引，适用于分布式部署；memory使用进程内索引，适用于单机部署
+type SearchConfig struct {
+	Engine string // 检索引擎类型（mysql或memory）
+}
+
+// CacheConfig 结构体表示群成员和用户资料缓存配置
+// memory使用进程内的LRU缓存，适用于单机部署；redis使用Redis缓存，适用于分布式部署
+type CacheConfig struct {
+	Engine string // 缓存类型（memory或redis）
+	Size   int    // 进程内缓存最多保存的条目数
+	Ttl    int    // 缓存的过期时间，单位为秒
+}
+
+// MessageConfig 结构体表示消息撤回和编辑配置
+type MessageConfig struct {
+	RecallWindow int // 发送者可以撤回消息的时间窗口，单位为秒，为0时使用默认的2分钟；群主和管理员撤回成员的消息不受限制
 }
 
 // c 是一个TomlConfig类型的全局变量，用于存储读取到的配置信息
@@ -66,6 +125,11 @@ func init() {
 
 	// 将读取到的配置文件内容解析到c变量中
 	viper.Unmarshal(&c)
+
+	// 没有配置节点id时使用主机名，容器部署时主机名即容器id
+	if c.MsgChannelType.NodeId == "" {
+		c.MsgChannelType.NodeId, _ = os.Hostname()
+	}
 }
 
 // GetConfig 函数用于获取全局的配置实例
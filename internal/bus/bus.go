@@ -0,0 +1,59 @@
+package bus
+
+import "sync" // 引入同步包，用于保护订阅者
+
+// Handler 是订阅者处理消息的回调函数
+type Handler func(data []byte)
+
+// DeliveryFunc 在消息发布成功或最终失败时调用，成功时err为nil，可能在其他goroutine中调用
+type DeliveryFunc func(err error)
+
+// MessageBus 是节点之间传递消息的总线，Server通过它发布消息并接收需要投递给本节点客户端的消息
+// key为会话标识，支持分区的实现用它保证同一会话内的消息有序；done用于获取发布结果，可以为nil
+type MessageBus interface {
+	Publish(key string, data []byte, done DeliveryFunc) // 发布消息，结果通过done通知
+	Subscribe(handler Handler) error                    // 订阅消息，收到的消息交给handler处理
+	Close() error                                       // 关闭总线，释放连接
+}
+
+// channelBus 是基于Go函数调用的单机总线，发布的消息直接交给本进程的订阅者
+type channelBus struct {
+	mutex    sync.RWMutex
+	handlers []Handler
+}
+
+// NewChannelBus 创建单机总线，对应gochannel模式
+func NewChannelBus() MessageBus {
+	return &channelBus{}
+}
+
+// Publish 方法同步调用所有订阅者，调用完成后通知发布成功
+func (b *channelBus) Publish(key string, data []byte, done DeliveryFunc) {
+	b.mutex.RLock()
+	for _, handler := range b.handlers {
+		handler(data)
+	}
+	b.mutex.RUnlock()
+
+	if done != nil {
+		done(nil)
+	}
+}
+
+// Subscribe 方法登记订阅者
+func (b *channelBus) Subscribe(handler Handler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+	return nil
+}
+
+// Close 方法清空订阅者
+func (b *channelBus) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.handlers = nil
+	return nil
+}
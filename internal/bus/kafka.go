@@ -0,0 +1,79 @@
+package bus
+
+import (
+	"chat-room/internal/kafka" // 引入Kafka包，用于生产和消费消息
+	"sync"                     // 引入同步包，用于等待消息转发到所有节点
+)
+
+// RouteFunc 根据消息内容返回消息的key和需要接收该消息的节点id，返回错误时不提交offset，稍后重新路由
+type RouteFunc func(data []byte) (string, []string, error)
+
+// kafkaBus 是基于Kafka的总线：消息以会话为key发布到路由主题，所有节点组成的消费者组共同消费路由主题，
+// 由route决定转发到哪些节点的主题；每个节点以独立的消费者组消费自己的主题
+type kafkaBus struct {
+	hosts  string
+	group  string
+	nodeId string
+	route  RouteFunc
+}
+
+// NewKafkaBus 创建Kafka总线，初始化生产者并确保路由主题和本节点的主题存在
+func NewKafkaBus(hosts, topic, group, nodeId string, partitions int32, options kafka.ProducerOptions, route RouteFunc) (MessageBus, error) {
+	if err := kafka.InitProducer(topic, hosts, options); err != nil {
+		return nil, err
+	}
+	kafka.EnsureTopic(hosts, kafka.Topic(), partitions)
+	kafka.EnsureTopic(hosts, kafka.NodeTopic(nodeId), 1)
+	return &kafkaBus{hosts: hosts, group: group, nodeId: nodeId, route: route}, nil
+}
+
+// Publish 方法把消息发布到路由主题，同一会话的消息进入同一分区，收到Kafka的确认后通知发布结果
+func (b *kafkaBus) Publish(key string, data []byte, done DeliveryFunc) {
+	kafka.Send(key, data, kafka.Callback(done))
+}
+
+// Subscribe 方法加入路由主题的消费者组，并以独立的消费者组消费本节点的主题
+// 路由主题中的消息转发到所有相关节点并收到Kafka确认后才提交offset，查询节点或转发失败时重新路由，节点可能收到重复的消息
+func (b *kafkaBus) Subscribe(handler Handler) error {
+	kafka.ConsumerGroup(b.hosts, b.group, []string{kafka.Topic()}, func(data []byte) error {
+		key, nodes, err := b.route(data)
+		if err != nil {
+			return err
+		}
+		return forward(nodes, key, data)
+	})
+	kafka.ConsumerGroup(b.hosts, b.group+"."+b.nodeId, []string{kafka.NodeTopic(b.nodeId)}, func(data []byte) error {
+		handler(data)
+		return nil
+	})
+	return nil
+}
+
+// forward 函数把消息转发到每个节点的主题，等待所有转发都收到Kafka的确认，返回第一个失败的错误
+func forward(nodes []string, key string, data []byte) error {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var firstErr error
+	wg.Add(len(nodes))
+	for _, nodeId := range nodes {
+		kafka.SendToNode(nodeId, key, data, func(err error) {
+			if err != nil {
+				mutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mutex.Unlock()
+			}
+			wg.Done()
+		})
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Close 方法关闭消费者组和生产者
+func (b *kafkaBus) Close() error {
+	kafka.CloseConsumer()
+	kafka.Close()
+	return nil
+}
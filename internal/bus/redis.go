@@ -0,0 +1,53 @@
+package bus
+
+import (
+	"context" // 引入上下文包，go-redis的命令需要传入上下文
+
+	"github.com/go-redis/redis/v8" // 引入Redis客户端
+)
+
+// redisBus 是基于Redis发布订阅的总线，每个节点订阅同一个频道，只投递给本节点连接的客户端
+// 发布订阅不持久化消息，节点离线期间的消息由离线消息补发机制从数据库中补齐
+type redisBus struct {
+	client  *redis.Client
+	channel string
+	pubsub  *redis.PubSub
+}
+
+// NewRedisBus 创建Redis总线，channel为所有节点共同订阅的频道
+func NewRedisBus(client *redis.Client, channel string) MessageBus {
+	return &redisBus{client: client, channel: channel}
+}
+
+// Publish 方法把消息发布到频道，Redis按发布顺序推送给订阅者，key不需要使用
+func (b *redisBus) Publish(key string, data []byte, done DeliveryFunc) {
+	err := b.client.Publish(context.Background(), b.channel, data).Err()
+	if done != nil {
+		done(err)
+	}
+}
+
+// Subscribe 方法订阅频道，确认订阅成功后在后台goroutine中把消息交给handler
+func (b *redisBus) Subscribe(handler Handler) error {
+	pubsub := b.client.Subscribe(context.Background(), b.channel)
+	if _, err := pubsub.Receive(context.Background()); err != nil { // 等待订阅确认，避免订阅前发布的消息丢失
+		pubsub.Close()
+		return err
+	}
+	b.pubsub = pubsub
+
+	go func() {
+		for msg := range pubsub.Channel() { // 订阅关闭后Channel会被关闭
+			handler([]byte(msg.Payload))
+		}
+	}()
+	return nil
+}
+
+// Close 方法取消订阅并关闭连接
+func (b *redisBus) Close() error {
+	if b.pubsub != nil {
+		b.pubsub.Close()
+	}
+	return b.client.Close()
+}
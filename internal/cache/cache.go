@@ -0,0 +1,14 @@
+package cache
+
+import "time" // 引入时间包，用于定义缓存的过期时间
+
+// Cache 接口定义了键值缓存，值为序列化后的字节，不同的实现可以按部署方式替换
+// 缓存只用于减少数据库查询，读取失败时按未命中处理，由调用者回源数据库
+type Cache interface {
+	Get(key string) ([]byte, bool) // 读取缓存，未命中或已过期时返回false
+	Set(key string, value []byte)  // 写入缓存，超过过期时间后失效
+	Delete(keys ...string)         // 删除缓存，数据变更后调用使缓存失效
+}
+
+// DEFAULT_TTL 没有配置时缓存的过期时间
+const DEFAULT_TTL = 5 * time.Minute
@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"container/list" // 引入双向链表，用于维护最近使用顺序
+	"sync"           // 引入同步包，用于保护缓存
+	"time"           // 引入时间包，用于判断缓存是否过期
+)
+
+// DEFAULT_LRU_SIZE 没有配置时进程内缓存最多保存的条目数
+const DEFAULT_LRU_SIZE = 10000
+
+// lruCache 结构体是进程内的LRU缓存，适用于单机部署，超过容量时淘汰最久未使用的条目
+// 多节点部署时只能使本节点的缓存失效，其他节点的旧数据在过期后才会刷新
+type lruCache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List               // 按使用时间排列的条目，表头为最近使用
+	entries map[string]*list.Element // 键到链表节点的映射
+}
+
+// lruEntry 是LRU缓存中的一个条目
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRUCache 函数创建进程内缓存，size和ttl不大于0时使用默认值
+func NewLRUCache(size int, ttl time.Duration) Cache {
+	if size <= 0 {
+		size = DEFAULT_LRU_SIZE
+	}
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get 方法读取缓存，命中时将条目移到表头，已过期的条目直接删除
+func (l *lruCache) Get(key string) ([]byte, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		l.order.Remove(element)
+		delete(l.entries, key)
+		return nil, false
+	}
+	l.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set 方法写入缓存，超过容量时淘汰表尾最久未使用的条目
+func (l *lruCache) Set(key string, value []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	expiresAt := time.Now().Add(l.ttl)
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		l.order.MoveToFront(element)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Delete 方法删除缓存
+func (l *lruCache) Delete(keys ...string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, key := range keys {
+		if element, ok := l.entries[key]; ok {
+			l.order.Remove(element)
+			delete(l.entries, key)
+		}
+	}
+}
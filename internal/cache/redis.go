@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"chat-room/pkg/global/log" // 引入全局日志记录器，用于记录Redis错误
+	"context"                  // 引入上下文包，Redis命令需要传入上下文
+	"time"                     // 引入时间包，用于设置缓存的过期时间
+
+	"github.com/go-redis/redis/v8" // 引入Redis客户端
+)
+
+// redisCache 结构体是基于Redis的缓存，适用于分布式部署，所有节点共享缓存，任一节点的数据变更会使所有节点的缓存失效
+type redisCache struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisCache 函数创建Redis缓存，所有键都加上prefix前缀，ttl不大于0时使用默认值
+func NewRedisCache(client *redis.Client, prefix string, ttl time.Duration) Cache {
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+	return &redisCache{client: client, prefix: prefix, ttl: ttl}
+}
+
+// Get 方法读取缓存，Redis不可用时按未命中处理
+func (r *redisCache) Get(key string) ([]byte, bool) {
+	value, err := r.client.Get(context.Background(), r.prefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Logger.Error("redis cache get error", log.String("redis cache get error", err.Error()))
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+// Set 方法写入缓存并设置过期时间
+func (r *redisCache) Set(key string, value []byte) {
+	if err := r.client.Set(context.Background(), r.prefix+key, value, r.ttl).Err(); err != nil {
+		log.Logger.Error("redis cache set error", log.String("redis cache set error", err.Error()))
+	}
+}
+
+// Delete 方法删除缓存，删除失败时旧数据在过期后失效
+func (r *redisCache) Delete(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	if err := r.client.Del(context.Background(), prefixed...).Err(); err != nil {
+		log.Logger.Error("redis cache delete error", log.String("redis cache delete error", err.Error()))
+	}
+}
@@ -0,0 +1,23 @@
+package pool
+
+import (
+	"chat-room/config" // 引入配置包，用于读取Redis配置信息
+
+	"github.com/go-redis/redis/v8" // 引入Redis客户端
+)
+
+var _redis *redis.Client // 定义一个全局变量，存储Redis客户端实例
+
+// init 函数在包被初始化时自动执行，创建Redis客户端，客户端在第一次执行命令时才建立连接
+func init() {
+	_redis = redis.NewClient(&redis.Options{
+		Addr:     config.GetConfig().Redis.Addr,     // Redis地址
+		Password: config.GetConfig().Redis.Password, // Redis密码
+		DB:       config.GetConfig().Redis.DB,       // Redis数据库编号
+	})
+}
+
+// GetRedis 函数用于返回全局的Redis客户端实例
+func GetRedis() *redis.Client {
+	return _redis
+}
@@ -0,0 +1,57 @@
+package hub
+
+import "sync" // 引入同步包，用于等待worker退出
+
+// Dispatcher 把需要路由的消息按key分配给固定数量的worker并发处理
+// 相同key的消息总是由同一个worker按到达顺序处理，以会话为key即可保证会话内有序
+type Dispatcher struct {
+	queues []chan interface{}
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher 创建并启动workers个worker，每个worker的队列长度为queueSize，队列满时Dispatch阻塞
+func NewDispatcher(workers, queueSize int, handle func(item interface{})) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	d := &Dispatcher{queues: make([]chan interface{}, workers)}
+	for i := range d.queues {
+		queue := make(chan interface{}, queueSize)
+		d.queues[i] = queue
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for item := range queue {
+				handle(item)
+			}
+		}()
+	}
+	return d
+}
+
+// Dispatch 方法把消息放入key对应的worker队列
+func (d *Dispatcher) Dispatch(key string, item interface{}) {
+	d.queues[Shard(key, len(d.queues))] <- item
+}
+
+// Workers 方法返回worker的数量
+func (d *Dispatcher) Workers() int {
+	return len(d.queues)
+}
+
+// Pending 方法返回所有worker队列中等待处理的消息数
+func (d *Dispatcher) Pending() int {
+	pending := 0
+	for _, queue := range d.queues {
+		pending += len(queue)
+	}
+	return pending
+}
+
+// Close 方法关闭所有worker队列，等待已入队的消息处理完成，关闭后不能再调用Dispatch
+func (d *Dispatcher) Close() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,163 @@
+package hub
+
+import "sync" // 引入同步包，用于保护每个分片
+
+// Session 是登记在注册表中的一个连接，同一用户的每个设备各自对应一个Session
+type Session interface {
+	User() string   // 用户uuid
+	Device() string // 设备id
+}
+
+// Registry 是按用户uuid分片的连接注册表，每个分片使用读写锁，投递消息时的查询可以并发进行
+type Registry struct {
+	shards []*registryShard
+}
+
+// registryShard 是注册表的一个分片，以用户uuid和设备id为键
+type registryShard struct {
+	mutex sync.RWMutex
+	users map[string]map[string]Session
+}
+
+// NewRegistry 创建注册表，shards小于1时只使用一个分片
+func NewRegistry(shards int) *Registry {
+	if shards < 1 {
+		shards = 1
+	}
+	r := &Registry{shards: make([]*registryShard, shards)}
+	for i := range r.shards {
+		r.shards[i] = &registryShard{users: make(map[string]map[string]Session)}
+	}
+	return r
+}
+
+// Shard 函数使用FNV-1a哈希返回key所在的分片，相同的key总是落在同一个分片，计算过程不分配内存
+func Shard(key string, shards int) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return int(hash % uint32(shards))
+}
+
+func (r *Registry) shard(user string) *registryShard {
+	return r.shards[Shard(user, len(r.shards))]
+}
+
+// Add 方法登记连接，返回同一设备被替换掉的旧连接（没有时为nil），以及是否为该用户第一个在线的设备
+func (r *Registry) Add(session Session) (Session, bool) {
+	shard := r.shard(session.User())
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	devices, ok := shard.users[session.User()]
+	if !ok {
+		devices = make(map[string]Session)
+		shard.users[session.User()] = devices
+	}
+	old := devices[session.Device()]
+	devices[session.Device()] = session
+	return old, len(devices) == 1
+}
+
+// Remove 方法注销连接，只有当前登记的连接就是session时才会删除
+// 返回是否删除了session，以及删除后该用户是否已没有在线的设备；session已被同一设备的新连接替换时两者都为false
+func (r *Registry) Remove(session Session) (removed bool, lastDevice bool) {
+	shard := r.shard(session.User())
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	devices, ok := shard.users[session.User()]
+	if !ok || devices[session.Device()] != session {
+		return false, false
+	}
+	delete(devices, session.Device())
+	if len(devices) == 0 {
+		delete(shard.users, session.User())
+		return true, true
+	}
+	return true, false
+}
+
+// Get 方法获取用户指定设备的连接
+func (r *Registry) Get(user, device string) (Session, bool) {
+	shard := r.shard(user)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	session, ok := shard.users[user][device]
+	return session, ok
+}
+
+// Devices 方法返回用户所有在线设备的连接快照，调用者可以在不持有锁的情况下遍历
+func (r *Registry) Devices(user string) []Session {
+	shard := r.shard(user)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+
+	devices := shard.users[user]
+	if len(devices) == 0 {
+		return nil
+	}
+	sessions := make([]Session, 0, len(devices))
+	for _, session := range devices {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// ForEach 方法批量遍历多个用户所有在线设备的连接，按分片分组后每个分片只加一次读锁，不为每个用户复制快照
+// f在持有分片读锁时调用，只能做非阻塞的操作，不能调用Add和Remove
+func (r *Registry) ForEach(users []string, f func(session Session)) {
+	batches := make([][]string, len(r.shards))
+	for _, user := range users {
+		index := Shard(user, len(r.shards))
+		batches[index] = append(batches[index], user)
+	}
+
+	for index, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+		shard := r.shards[index]
+		shard.mutex.RLock()
+		for _, user := range batch {
+			for _, session := range shard.users[user] {
+				f(session)
+			}
+		}
+		shard.mutex.RUnlock()
+	}
+}
+
+// Range 方法逐个分片获取快照后遍历所有连接，f中可以再调用注册表的其他方法
+func (r *Registry) Range(f func(session Session)) {
+	for _, shard := range r.shards {
+		shard.mutex.RLock()
+		sessions := make([]Session, 0, len(shard.users))
+		for _, devices := range shard.users {
+			for _, session := range devices {
+				sessions = append(sessions, session)
+			}
+		}
+		shard.mutex.RUnlock()
+
+		for _, session := range sessions {
+			f(session)
+		}
+	}
+}
+
+// Len 方法返回在线的连接数
+func (r *Registry) Len() int {
+	count := 0
+	for _, shard := range r.shards {
+		shard.mutex.RLock()
+		for _, devices := range shard.users {
+			count += len(devices)
+		}
+		shard.mutex.RUnlock()
+	}
+	return count
+}
@@ -1,48 +1,126 @@
 package kafka
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"chat-room/pkg/global/log"
 	"github.com/Shopify/sarama"
-	"strings"
 )
 
-var consumer sarama.Consumer
+// groups 保存所有启动的消费者组，关闭时统一释放
+var groups []sarama.ConsumerGroup
 
-type ConsumerCallback func(data []byte)
+// ConsumerCallback 处理一条消息，返回错误时不提交该消息的offset，稍后重试
+type ConsumerCallback func(data []byte) error
 
-// 初始化消费者
-func InitConsumer(hosts string) {
+// retryInterval 是消息处理失败后重试的间隔
+const retryInterval = time.Second
+
+// newConfig 创建公共的sarama配置，消费者组需要Kafka 0.10.2以上、zstd压缩需要2.1以上的协议版本
+func newConfig() *sarama.Config {
 	config := sarama.NewConfig()
-	client, err := sarama.NewClient(strings.Split(hosts, ","), config)
+	config.Version = sarama.V2_1_0_0
+	return config
+}
+
+// EnsureTopic 确保主题存在，节点主题在节点第一次启动时创建
+func EnsureTopic(hosts, topicName string, partitions int32) {
+	admin, err := sarama.NewClusterAdmin(strings.Split(hosts, ","), newConfig())
 	if nil != err {
-		log.Logger.Error("init kafka consumer client error", log.Any("init kafka consumer client error", err.Error()))
+		log.Logger.Error("init kafka admin error", log.Any("init kafka admin error", err.Error()))
+		return
+	}
+	defer admin.Close()
+
+	if partitions <= 0 {
+		partitions = 1
 	}
 
-	consumer, err = sarama.NewConsumerFromClient(client)
+	err = admin.CreateTopic(topicName, &sarama.TopicDetail{NumPartitions: partitions, ReplicationFactor: 1}, false)
 	if nil != err {
-		log.Logger.Error("init kafka consumer error", log.Any("init kafka consumer error", err.Error()))
+		if topicErr, ok := err.(*sarama.TopicError); ok && topicErr.Err == sarama.ErrTopicAlreadyExists {
+			return
+		}
+		log.Logger.Error("create kafka topic error", log.String("topic", topicName), log.Any("create kafka topic error", err.Error()))
 	}
 }
 
-// 消费消息，通过回调函数进行
-func ConsumerMsg(callBack ConsumerCallback) {
-	partitionConsumer, err := consumer.ConsumePartition(topic, 0, sarama.OffsetNewest)
+// ConsumerGroup 以消费者组的方式消费主题，处理成功后才提交offset，重启后从上次提交的位置继续消费
+// 相同groupId的多个节点共同分担主题的分区，不同groupId各自消费全部消息；消费在后台goroutine中进行
+func ConsumerGroup(hosts, groupId string, topics []string, callBack ConsumerCallback) {
+	config := newConfig()
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest // 新的消费者组从最新位置开始消费
+	config.Consumer.Return.Errors = true
+	group, err := sarama.NewConsumerGroup(strings.Split(hosts, ","), groupId, config)
 	if nil != err {
-		log.Logger.Error("iConsumePartition error", log.Any("ConsumePartition error", err.Error()))
+		log.Logger.Error("init kafka consumer group error", log.Any("init kafka consumer group error", err.Error()))
 		return
 	}
+	groups = append(groups, group)
+
+	go func() {
+		for err := range group.Errors() {
+			log.Logger.Error("kafka consumer group error", log.String("group", groupId), log.Any("kafka consumer group error", err.Error()))
+		}
+	}()
+
+	handler := &groupHandler{callBack: callBack}
+	go func() {
+		for {
+			// 分区重新分配时Consume会返回，需要循环重新加入消费者组
+			if err := group.Consume(context.Background(), topics, handler); err != nil {
+				if err == sarama.ErrClosedConsumerGroup {
+					return
+				}
+				log.Logger.Error("kafka consume error", log.String("group", groupId), log.Any("kafka consume error", err.Error()))
+				time.Sleep(time.Second)
+			}
+		}
+	}()
+}
+
+// groupHandler 实现sarama.ConsumerGroupHandler，逐条回调，回调成功后才标记offset
+type groupHandler struct {
+	callBack ConsumerCallback
+}
+
+func (h *groupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if !h.handle(session, msg) {
+			return nil // 会话已结束，未标记的消息在重新分配分区后从上次提交的offset重新消费
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
 
-	defer partitionConsumer.Close()
+// handle 方法回调一条消息，失败时按retryInterval重试，直到成功或消费者组会话结束
+// offset是按分区累计提交的，失败的消息不能跳过，否则后续消息的offset会把它一起提交
+func (h *groupHandler) handle(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) bool {
+	if nil == h.callBack {
+		return true
+	}
 	for {
-		msg := <-partitionConsumer.Messages()
-		if nil != callBack {
-			callBack(msg.Value)
+		err := h.callBack(msg.Value)
+		if err == nil {
+			return true
+		}
+		log.Logger.Error("kafka consume message error", log.String("topic", msg.Topic), log.Any("offset", msg.Offset), log.Any("kafka consume message error", err.Error()))
+		select {
+		case <-session.Context().Done():
+			return false
+		case <-time.After(retryInterval):
 		}
 	}
 }
 
 func CloseConsumer() {
-	if nil != consumer {
-		consumer.Close()
+	for _, group := range groups {
+		group.Close()
 	}
 }
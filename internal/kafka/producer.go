@@ -1,8 +1,11 @@
 package kafka
 
 import (
+	"expvar"
 	"strings"
+	"sync"
 
+	"chat-room/pkg/errors"
 	"chat-room/pkg/global/log"
 	"github.com/Shopify/sarama"
 )
@@ -10,28 +13,165 @@ import (
 var producer sarama.AsyncProducer
 var topic string = "default_message"
 
-func InitProducer(topicInput, hosts string) {
+// producerMutex 保护producer的关闭，关闭后发送的消息直接返回失败，避免向已关闭的生产者写入
+var producerMutex sync.RWMutex
+
+// 生产者指标，通过/debug/vars查看
+var (
+	inFlight  = expvar.NewInt("kafka_producer_in_flight") // 已发送但还没有收到确认的消息数
+	succeeded = expvar.NewInt("kafka_producer_succeeded") // 发送成功的消息数
+	failed    = expvar.NewInt("kafka_producer_failed")    // 重试后仍然发送失败的消息数
+)
+
+// Callback 在消息发送成功或最终失败时调用，成功时err为nil
+type Callback func(err error)
+
+// ProducerOptions 生产者的可靠性配置
+type ProducerOptions struct {
+	Acks        string // 确认级别：all等待所有同步副本，leader只等待leader，none不等待
+	Retries     int    // 发送失败时的重试次数
+	Idempotent  bool   // 是否开启幂等生产，避免重试导致消息重复，要求Acks为all
+	Compression string // 压缩算法：none、gzip、snappy、lz4或zstd
+}
+
+// newProducerConfig 根据配置创建生产者的sarama配置
+func newProducerConfig(options ProducerOptions) (*sarama.Config, error) {
+	config := newConfig()
+	config.Producer.Return.Successes = true // 需要成功回执来通知发送者并统计在途消息
+	config.Producer.Return.Errors = true
+	config.Producer.Partitioner = sarama.NewHashPartitioner // 相同key的消息进入同一分区，保证同一会话内的消息有序
+	config.Producer.Retry.Max = options.Retries
+
+	switch options.Acks {
+	case "", "all":
+		config.Producer.RequiredAcks = sarama.WaitForAll
+	case "leader":
+		config.Producer.RequiredAcks = sarama.WaitForLocal
+	case "none":
+		config.Producer.RequiredAcks = sarama.NoResponse
+	default:
+		return nil, errors.New("unsupported kafka acks: " + options.Acks)
+	}
+
+	switch options.Compression {
+	case "", "gzip":
+		config.Producer.Compression = sarama.CompressionGZIP
+	case "none":
+		config.Producer.Compression = sarama.CompressionNone
+	case "snappy":
+		config.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		config.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		config.Producer.Compression = sarama.CompressionZSTD
+	default:
+		return nil, errors.New("unsupported kafka compression: " + options.Compression)
+	}
+
+	if options.Idempotent {
+		if config.Producer.RequiredAcks != sarama.WaitForAll {
+			return nil, errors.New("kafka idempotent producer requires acks = all")
+		}
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1 // 幂等生产要求每个连接只有一个在途请求
+	}
+	return config, config.Validate()
+}
+
+// InitProducer 初始化异步生产者，并启动处理发送结果的goroutine
+func InitProducer(topicInput, hosts string, options ProducerOptions) error {
 	topic = topicInput
-	config := sarama.NewConfig()
-	config.Producer.Compression = sarama.CompressionGZIP
+	config, err := newProducerConfig(options)
+	if nil != err {
+		return err
+	}
 	client, err := sarama.NewClient(strings.Split(hosts, ","), config)
 	if nil != err {
-		log.Logger.Error("init kafka client error", log.Any("init kafka client error", err.Error()))
+		return errors.New("init kafka client error: " + err.Error())
 	}
 
 	producer, err = sarama.NewAsyncProducerFromClient(client)
 	if nil != err {
-		log.Logger.Error("init kafka async client error", log.Any("init kafka async client error", err.Error()))
+		return errors.New("init kafka async producer error: " + err.Error())
+	}
+
+	go handleSuccesses(producer)
+	go handleErrors(producer)
+	return nil
+}
+
+// handleSuccesses 处理发送成功的回执，直到生产者关闭
+func handleSuccesses(p sarama.AsyncProducer) {
+	for msg := range p.Successes() {
+		inFlight.Add(-1)
+		succeeded.Add(1)
+		if callback, ok := msg.Metadata.(Callback); ok && callback != nil {
+			callback(nil)
+		}
+	}
+}
+
+// handleErrors 处理重试后仍然失败的消息，直到生产者关闭
+func handleErrors(p sarama.AsyncProducer) {
+	for err := range p.Errors() {
+		inFlight.Add(-1)
+		failed.Add(1)
+		log.Logger.Error("kafka send message error", log.String("topic", err.Msg.Topic), log.Any("kafka send message error", err.Err.Error()))
+		if callback, ok := err.Msg.Metadata.(Callback); ok && callback != nil {
+			callback(err.Err)
+		}
 	}
 }
 
-func Send(data []byte) {
+// Send 发送消息到路由主题，key为会话标识，同一会话的消息有序
+func Send(key string, data []byte, callback Callback) {
+	SendTo(topic, key, data, callback)
+}
+
+// SendToNode 发送消息到指定节点的主题，只有该节点会消费
+func SendToNode(nodeId, key string, data []byte, callback Callback) {
+	SendTo(NodeTopic(nodeId), key, data, callback)
+}
+
+// SendTo 发送消息到指定主题，发送结果通过callback异步通知，callback可以为nil
+func SendTo(topicName, key string, data []byte, callback Callback) {
+	producerMutex.RLock()
+	defer producerMutex.RUnlock()
+
+	if producer == nil {
+		failed.Add(1)
+		if callback != nil {
+			callback(errors.New("kafka producer is not initialized or closed"))
+		}
+		return
+	}
+
 	be := sarama.ByteEncoder(data)
-	producer.Input() <- &sarama.ProducerMessage{Topic: topic, Key: nil, Value: be}
+	msg := &sarama.ProducerMessage{Topic: topicName, Value: be, Metadata: callback}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	inFlight.Add(1)
+	producer.Input() <- msg
 }
 
+// NodeTopic 返回节点专属的主题名称
+func NodeTopic(nodeId string) string {
+	return topic + "." + nodeId
+}
+
+// Topic 返回路由主题名称
+func Topic() string {
+	return topic
+}
+
+// Close 关闭生产者，关闭前会等待在途消息发送完成
 func Close() {
+	producerMutex.Lock()
+	defer producerMutex.Unlock()
+
 	if producer != nil {
 		producer.Close()
+		producer = nil
 	}
 }
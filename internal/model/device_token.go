@@ -0,0 +1,13 @@
+package model
+
+import "time" // 引入时间包，用于处理时间相关操作
+
+// DeviceToken 结构体记录用户每个设备的token版本，token中携带签发时的版本，设备被远程下线后版本增加，该设备已签发的token全部失效
+type DeviceToken struct {
+	ID        int32     `json:"id" gorm:"primarykey"`                                                            // ID为主键，使用整型，自增
+	CreatedAt time.Time `json:"createAt"`                                                                        // CreatedAt记录设备第一次登录的时间
+	UpdatedAt time.Time `json:"updatedAt"`                                                                       // UpdatedAt记录最后更新的时间
+	UserUuid  string    `json:"userUuid" gorm:"type:varchar(150);uniqueIndex:idx_device_token;comment:'用户uuid'"` // UserUuid为设备所属的用户uuid
+	DeviceId  string    `json:"deviceId" gorm:"type:varchar(150);uniqueIndex:idx_device_token;comment:'设备id'"`   // DeviceId为设备id
+	Version   int32     `json:"version" gorm:"default:0;comment:'token版本'"`                                      // Version为设备当前有效的token版本
+}
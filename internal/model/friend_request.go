@@ -0,0 +1,14 @@
+package model
+
+import "time" // 引入时间包，用于处理时间相关操作
+
+// FriendRequest 结构体表示好友请求的数据模型，对方通过后双方各自创建一条好友关系
+type FriendRequest struct {
+	ID        int32     `json:"id" gorm:"primarykey"`                                     // ID为主键，使用整型，自增
+	CreatedAt time.Time `json:"createAt"`                                                 // CreatedAt记录发送好友请求的时间
+	UpdatedAt time.Time `json:"updatedAt"`                                                // UpdatedAt记录好友请求被处理的时间
+	FromId    int32     `json:"fromId" gorm:"index;comment:'发送者ID'"`                      // FromId为发送好友请求的用户ID
+	ToId      int32     `json:"toId" gorm:"index;comment:'接收者ID'"`                        // ToId为接收好友请求的用户ID
+	Reason    string    `json:"reason" gorm:"type:varchar(350);comment:'验证消息'"`           // Reason为发送者填写的验证消息
+	Status    int16     `json:"status" gorm:"default:0;comment:'状态：0待处理，1已通过，2已拒绝，3已取消'"` // Status为好友请求的处理状态
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete" // 引入GORM的软删除插件，用于实现逻辑删除功能
+	"time"                       // 引入时间包，用于处理时间相关操作
+)
+
+// GroupInvite 结构体表示群组邀请链接的数据模型，持有邀请码的用户可以不经审核直接加入群组
+type GroupInvite struct {
+	ID        int32                 `json:"id" gorm:"primarykey"`                                            // ID为主键，使用整型，自增
+	CreatedAt time.Time             `json:"createAt"`                                                        // CreatedAt记录邀请链接的创建时间
+	UpdatedAt time.Time             `json:"updatedAt"`                                                       // UpdatedAt记录邀请链接的最后更新时间
+	DeletedAt soft_delete.DeletedAt `json:"deletedAt"`                                                       // DeletedAt用于软删除字段，撤销的邀请链接被逻辑删除
+	Code      string                `json:"code" gorm:"type:varchar(64);not null;uniqueIndex;comment:'邀请码'"` // Code为邀请码，出现在邀请链接中
+	GroupId   int32                 `json:"groupId" gorm:"index;comment:'群组ID'"`                             // GroupId为邀请加入的群组ID
+	UserId    int32                 `json:"userId" gorm:"comment:'创建者ID'"`                                   // UserId为创建邀请链接的群主或管理员ID
+	ExpiresAt int64                 `json:"expiresAt" gorm:"default:0;comment:'过期时间'"`                       // ExpiresAt为邀请链接过期的毫秒时间戳，0表示永不过期
+	MaxUses   int32                 `json:"maxUses" gorm:"default:0;comment:'最大使用次数'"`                       // MaxUses为邀请链接最多可以使用的次数，0表示不限次数
+	Uses      int32                 `json:"uses" gorm:"default:0;comment:'已使用次数'"`                           // Uses为通过邀请链接加入群组的人数
+}
+
+// Usable 方法判断邀请链接在now（毫秒时间戳）时是否仍然可以使用
+func (i *GroupInvite) Usable(now int64) bool {
+	if i.ExpiresAt != 0 && now >= i.ExpiresAt {
+		return false
+	}
+	return i.MaxUses == 0 || i.Uses < i.MaxUses
+}
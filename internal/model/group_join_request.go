@@ -0,0 +1,15 @@
+package model
+
+import "time" // 引入时间包，用于处理时间相关操作
+
+// GroupJoinRequest 结构体表示加群申请的数据模型，群组需要审核时由申请加入的用户创建，群主或管理员通过或拒绝
+type GroupJoinRequest struct {
+	ID         int32     `json:"id" gorm:"primarykey"`                                // ID为主键，使用整型，自增
+	CreatedAt  time.Time `json:"createAt"`                                            // CreatedAt记录提交申请的时间
+	UpdatedAt  time.Time `json:"updatedAt"`                                           // UpdatedAt记录申请被处理的时间
+	GroupId    int32     `json:"groupId" gorm:"index;comment:'群组ID'"`                 // GroupId为申请加入的群组ID
+	UserId     int32     `json:"userId" gorm:"index;comment:'申请人ID'"`                 // UserId为申请加入群组的用户ID
+	Reason     string    `json:"reason" gorm:"type:varchar(350);comment:'申请理由'"`      // Reason为申请人填写的申请理由
+	Status     int16     `json:"status" gorm:"default:0;comment:'状态：0待审核，1已通过，2已拒绝'"` // Status为申请的处理状态
+	OperatorId int32     `json:"operatorId" gorm:"default:0;comment:'处理人ID'"`         // OperatorId为处理申请的群主或管理员ID，待审核时为0
+}
@@ -15,4 +15,11 @@ type GroupMember struct {
 	GroupId   int32                 `json:"groupId" gorm:"index;comment:'群组ID'"`            // GroupId为群组ID，标识成员所属群组，数据库中创建索引
 	Nickname  string                `json:"nickname" gorm:"type:varchar(350);comment:'昵称'"` // Nickname为成员在群中的昵称，使用字符串类型，最大长度350字符
 	Mute      int16                 `json:"mute" gorm:"comment:'是否禁言'"`                     // Mute标识成员是否被禁言，0表示未禁言，1表示已禁言
+	MuteUntil int64                 `json:"muteUntil" gorm:"default:0;comment:'禁言截止时间'"`    // MuteUntil为禁言截止的毫秒时间戳，0表示永久禁言，过期后禁言自动失效
+	Role      int16                 `json:"role" gorm:"default:0;comment:'角色'"`             // Role为成员角色，0表示普通成员，1表示管理员，2表示群主
+}
+
+// Muted 方法判断成员在now（毫秒时间戳）时是否处于禁言中，限时禁言到期后视为未禁言
+func (m *GroupMember) Muted(now int64) bool {
+	return m.Mute == 1 && (m.MuteUntil == 0 || now < m.MuteUntil)
 }
@@ -0,0 +1,18 @@
+package model
+
+import "time" // 引入时间包，用于处理时间相关操作
+
+// MessageCursor 结构体表示用户的设备在某个会话中的消息投递游标，以及用户在该会话中的已读位置
+// 投递游标按设备记录，每个设备各自补发离线消息；DeviceId为空的记录保存用户的已读位置，所有设备共享
+// MessageType为0、ConversationId为0的记录是设备（或用户）开始记录游标时的全局起点，没有会话游标时以它为准
+type MessageCursor struct {
+	ID             int32     `json:"id" gorm:"primarykey"`                                                                               // ID为主键，使用整型，自增
+	CreatedAt      time.Time `json:"createAt"`                                                                                           // CreatedAt记录游标的创建时间
+	UpdatedAt      time.Time `json:"updatedAt"`                                                                                          // UpdatedAt记录游标的最后更新时间
+	UserId         int32     `json:"userId" gorm:"uniqueIndex:idx_cursor_device;comment:'用户ID'"`                                         // UserId为游标所属的用户ID
+	DeviceId       string    `json:"deviceId" gorm:"type:varchar(150);default:'';uniqueIndex:idx_cursor_device;comment:'设备ID，为空时为已读位置'"` // DeviceId为投递游标所属的设备ID，已读位置的记录为空
+	MessageType    int16     `json:"messageType" gorm:"uniqueIndex:idx_cursor_device;comment:'会话类型：0全局起点，1单聊，2群聊'"`                      // MessageType标识会话类型
+	ConversationId int32     `json:"conversationId" gorm:"uniqueIndex:idx_cursor_device;comment:'会话ID：单聊为对方用户ID，群聊为群组ID'"`               // ConversationId为会话对端的用户ID或群组ID
+	LastMessageId  int32     `json:"lastMessageId" gorm:"comment:'已投递的最后一条消息ID'"`                                                        // LastMessageId为该会话中已投递给用户的最后一条消息ID
+	LastReadId     int32     `json:"lastReadId" gorm:"comment:'已读的最后一条消息ID'"`                                                            // LastReadId为该会话中用户已读的最后一条消息ID，用于计算未读数
+}
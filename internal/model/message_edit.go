@@ -0,0 +1,11 @@
+package model
+
+import "time" // 引入时间包，用于处理时间相关操作
+
+// MessageEdit 结构体表示消息的编辑历史，每次编辑前保存一条原来的内容
+type MessageEdit struct {
+	ID        int32     `json:"id" gorm:"primarykey"`                               // ID为主键，使用整型，自增
+	CreatedAt time.Time `json:"createAt"`                                           // CreatedAt记录编辑的时间
+	MessageId int32     `json:"messageId" gorm:"index;comment:'消息ID'"`              // MessageId为被编辑的消息ID
+	Content   string    `json:"content" gorm:"type:varchar(2500);comment:'编辑前的内容'"` // Content为本次编辑前的消息内容
+}
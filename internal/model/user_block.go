@@ -0,0 +1,16 @@
+package model
+
+import (
+	"gorm.io/plugin/soft_delete" // 引入GORM的软删除插件，用于实现逻辑删除功能
+	"time"                       // 引入时间包，用于处理时间相关操作
+)
+
+// UserBlock 结构体表示用户黑名单的数据模型，用户不会收到被屏蔽用户的单聊消息和好友请求
+type UserBlock struct {
+	ID        int32                 `json:"id" gorm:"primarykey"`                     // ID为主键，使用整型，自增
+	CreatedAt time.Time             `json:"createAt"`                                 // CreatedAt记录屏蔽的时间
+	UpdatedAt time.Time             `json:"updatedAt"`                                // UpdatedAt记录最后更新的时间
+	DeletedAt soft_delete.DeletedAt `json:"deletedAt"`                                // DeletedAt用于软删除字段，解除屏蔽后被逻辑删除
+	UserId    int32                 `json:"userId" gorm:"index;comment:'用户ID'"`       // UserId为屏蔽他人的用户ID
+	BlockedId int32                 `json:"blockedId" gorm:"index;comment:'被屏蔽用户ID'"` // BlockedId为被屏蔽的用户ID
+}
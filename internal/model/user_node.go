@@ -0,0 +1,14 @@
+package model
+
+import "time" // 引入时间包，用于处理时间相关操作
+
+// UserNode 结构体表示用户的设备连接所在的节点，Kafka模式下用于把消息只路由到用户所在的节点，多节点部署时用于统计用户在集群中的设备数
+type UserNode struct {
+	ID         int32     `json:"id" gorm:"primarykey"`                                                           // ID为主键，使用整型，自增
+	CreatedAt  time.Time `json:"createAt"`                                                                       // CreatedAt记录连接建立的时间
+	UserUuid   string    `json:"userUuid" gorm:"type:varchar(150);uniqueIndex:idx_user_device;comment:'用户uuid'"` // UserUuid为连接所属的用户uuid
+	DeviceId   string    `json:"deviceId" gorm:"type:varchar(150);uniqueIndex:idx_user_device;comment:'设备id'"`   // DeviceId为连接的设备id
+	DeviceType string    `json:"deviceType" gorm:"type:varchar(20);comment:'设备类型'"`                              // DeviceType为连接的设备类型
+	RemoteAddr string    `json:"remoteAddr" gorm:"type:varchar(150);comment:'远端地址'"`                             // RemoteAddr为连接的远端地址
+	NodeId     string    `json:"nodeId" gorm:"type:varchar(150);index;comment:'节点id'"`                           // NodeId为连接所在的节点id
+}
@@ -2,9 +2,15 @@ package router
 
 import (
 	"chat-room/api/v1"              // 引入API v1版本的路由处理函数
+	"chat-room/config"              // 引入配置包，用于读取token签名密钥
+	"chat-room/internal/service"    // 引入服务层，用于校验设备的token版本
+	"chat-room/pkg/common/constant" // 引入常量包，定义了token类型和上下文键名
 	"chat-room/pkg/common/response" // 引入通用响应包，用于统一格式化HTTP响应
+	"chat-room/pkg/common/util"     // 引入工具包，用于校验token
 	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"expvar"                        // 引入expvar包，用于暴露运行指标
 	"net/http"                      // 引入HTTP包，用于处理HTTP相关操作
+	"strings"                       // 引入字符串处理包，用于解析Authorization请求头
 
 	"github.com/gin-gonic/gin" // 引入Gin框架，用于处理HTTP请求
 	"go.uber.org/zap"          // 引入Zap日志库，用于日志记录
@@ -21,34 +27,79 @@ func NewRouter() *gin.Engine {
 
 	socket := RunSocekt // 定义WebSocket路由处理函数
 
-	group := server.Group("") // 定义一个基础路径分组
+	// 无需认证的路由
+	public := server.Group("")
+	{
+		public.POST("/user/register", v1.Register)          // 用户注册
+		public.POST("/user/login", v1.Login)                // 用户登录
+		public.POST("/user/token/refresh", v1.RefreshToken) // 刷新token
+	}
+
+	group := server.Group("", Auth()) // 定义一个基础路径分组，所有路由都需要携带有效的access token
 	{
 		// 用户相关路由
-		group.GET("/user", v1.GetUserList)               // 获取用户列表
-		group.GET("/user/:uuid", v1.GetUserDetails)      // 获取指定UUID用户的详细信息
-		group.GET("/user/name", v1.GetUserOrGroupByName) // 通过用户名或群组名获取信息
-		group.POST("/user/register", v1.Register)        // 用户注册
-		group.POST("/user/login", v1.Login)              // 用户登录
-		group.PUT("/user", v1.ModifyUserInfo)            // 修改用户信息
+		group.GET("/user", v1.GetUserList)                            // 获取用户列表
+		group.GET("/user/:uuid", v1.GetUserDetails)                   // 获取指定UUID用户的详细信息
+		group.GET("/user/name", v1.GetUserOrGroupByName)              // 通过用户名或群组名获取信息
+		group.GET("/user/:uuid/presence", v1.GetUserPresence)         // 获取指定用户的在线状态
+		group.GET("/user/session", v1.GetUserSessions)                // 获取当前用户在线的设备
+		group.DELETE("/user/session/:deviceId", v1.DeleteUserSession) // 将当前用户的指定设备远程下线
+		group.GET("/user/block", v1.GetBlockedUsers)                  // 获取当前用户的黑名单
+		group.PUT("/user/block/:uuid", v1.BlockUser)                  // 屏蔽用户
+		group.DELETE("/user/block/:uuid", v1.UnblockUser)             // 解除屏蔽
+		group.PUT("/user", v1.ModifyUserInfo)                         // 修改用户信息
 
 		// 好友相关路由
-		group.POST("/friend", v1.AddFriend) // 添加好友
+		group.POST("/friend", v1.AddFriend)                              // 发送好友请求
+		group.DELETE("/friend/:uuid", v1.DeleteFriend)                   // 删除好友
+		group.GET("/friend/request", v1.GetFriendRequests)               // 获取待处理的好友请求
+		group.POST("/friend/request/:id/accept", v1.AcceptFriendRequest) // 通过好友请求
+		group.POST("/friend/request/:id/reject", v1.RejectFriendRequest) // 拒绝好友请求
+		group.POST("/friend/request/:id/cancel", v1.CancelFriendRequest) // 取消好友请求
 
 		// 消息相关路由
-		group.GET("/message", v1.GetMessage) // 获取消息列表
+		group.GET("/message", v1.GetMessage)                    // 获取消息列表
+		group.GET("/message/search", v1.SearchMessage)          // 检索消息
+		group.PUT("/message/:id", v1.EditMessage)               // 编辑消息
+		group.DELETE("/message/:id", v1.RecallMessage)          // 撤回消息
+		group.GET("/message/:id/history", v1.GetMessageHistory) // 获取消息的编辑历史
+
+		// 会话相关路由
+		group.GET("/conversation", v1.GetConversations) // 获取会话列表和未读数
 
 		// 文件相关路由
 		group.GET("/file/:fileName", v1.GetFile) // 获取文件
 		group.POST("/file", v1.SaveFile)         // 上传文件
 
 		// 群组相关路由
-		group.GET("/group/:uuid", v1.GetGroup)                       // 获取群组信息
-		group.POST("/group/:uuid", v1.SaveGroup)                     // 保存群组信息
-		group.POST("/group/join/:userUuid/:groupUuid", v1.JoinGroup) // 加入群组
-		group.GET("/group/user/:uuid", v1.GetGroupUsers)             // 获取群组用户列表
+		group.GET("/group/:uuid", v1.GetGroup)                              // 获取群组信息
+		group.POST("/group/:uuid", v1.SaveGroup)                            // 保存群组信息
+		group.POST("/group/join/:userUuid/:groupUuid", v1.JoinGroup)        // 加入群组
+		group.GET("/group/user/:uuid", v1.GetGroupUsers)                    // 获取群组用户列表
+		group.POST("/group/:uuid/leave", v1.LeaveGroup)                     // 退出群组
+		group.DELETE("/group/:uuid/member/:userUuid", v1.KickGroupMember)   // 将成员移出群组
+		group.PUT("/group/:uuid/admin/:userUuid", v1.PromoteGroupMember)    // 设置管理员
+		group.DELETE("/group/:uuid/admin/:userUuid", v1.DemoteGroupMember)  // 取消管理员
+		group.PUT("/group/:uuid/owner/:userUuid", v1.TransferGroupOwner)    // 转让群主
+		group.DELETE("/group/:uuid", v1.DissolveGroup)                      // 解散群组
+		group.PUT("/group/:uuid/mute/:userUuid", v1.MuteGroupMember)        // 禁言成员
+		group.DELETE("/group/:uuid/mute/:userUuid", v1.UnmuteGroupMember)   // 解除成员禁言
+		group.PUT("/group/:uuid/mute", v1.MuteAllGroupMembers)              // 开启全员禁言
+		group.DELETE("/group/:uuid/mute", v1.UnmuteAllGroupMembers)         // 关闭全员禁言
+		group.PUT("/group/:uuid/policy", v1.SetGroupJoinPolicy)             // 修改加群方式
+		group.POST("/group/:uuid/invite", v1.CreateGroupInvite)             // 创建邀请链接
+		group.GET("/group/:uuid/invite", v1.GetGroupInvites)                // 获取可用的邀请链接
+		group.DELETE("/group/:uuid/invite/:code", v1.RevokeGroupInvite)     // 撤销邀请链接
+		group.POST("/group/invite/:code", v1.JoinGroupByInvite)             // 通过邀请链接加入群组
+		group.GET("/group/:uuid/request", v1.GetGroupJoinRequests)          // 获取待审核的加群申请
+		group.PUT("/group/:uuid/request/:id", v1.ApproveGroupJoinRequest)   // 通过加群申请
+		group.DELETE("/group/:uuid/request/:id", v1.RejectGroupJoinRequest) // 拒绝加群申请
 
 		// WebSocket相关路由
 		group.GET("/socket.io", socket) // WebSocket连接
+
+		// 运行指标，包括Kafka生产者的在途和失败消息数，包含进程的启动参数和内存信息，需要携带有效的access token
+		group.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 	}
 	return server // 返回配置好的Gin引擎
 }
@@ -91,3 +142,37 @@ func Recovery(c *gin.Context) {
 	}()
 	c.Next() // 执行下一个中间件或处理器
 }
+
+// Auth 中间件用于校验access token，校验通过后将用户uuid保存到上下文中
+// token优先从Authorization请求头（Bearer格式）中读取，浏览器的WebSocket和图片请求无法设置请求头，也可以通过token查询参数传递
+func Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == "OPTIONS" { // 预检请求已由Cors中间件处理
+			c.Abort()
+			return
+		}
+
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.Query("token")
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.FailCodeMsg(http.StatusUnauthorized, "未登录"))
+			return
+		}
+
+		claims, err := util.ParseToken(token, constant.ACCESS_TOKEN, config.GetConfig().Auth.Secret)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.FailCodeMsg(http.StatusUnauthorized, err.Error()))
+			return
+		}
+		if !service.UserService.CheckDeviceToken(claims.Uuid, claims.DeviceId, claims.Version) { // 设备被远程下线后，之前签发的token失效
+			c.AbortWithStatusJSON(http.StatusUnauthorized, response.FailCodeMsg(http.StatusUnauthorized, "设备已下线，请重新登录"))
+			return
+		}
+
+		c.Set(constant.CONTEXT_USER_UUID, claims.Uuid)     // 保存当前登录用户的uuid，供后续处理器使用
+		c.Set(constant.CONTEXT_DEVICE_ID, claims.DeviceId) // 保存token绑定的设备id，WebSocket连接使用该设备id
+		c.Next()
+	}
+}
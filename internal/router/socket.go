@@ -1,9 +1,11 @@
 package router
 
 import (
-	"chat-room/internal/server" // 引入服务器包，处理客户端连接和消息
-	"chat-room/pkg/global/log"  // 引入全局日志记录器，用于日志记录
-	"net/http"                  // 引入HTTP包，用于处理HTTP相关操作
+	"chat-room/internal/server"     // 引入服务器包，处理客户端连接和消息
+	"chat-room/pkg/common/constant" // 引入常量包，定义了上下文键名和设备类型
+	"chat-room/pkg/common/response" // 引入通用响应包，用于返回错误信息
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"net/http"                      // 引入HTTP包，用于处理HTTP相关操作
 
 	"github.com/gin-gonic/gin"     // 引入Gin框架，用于处理HTTP请求
 	"github.com/gorilla/websocket" // 引入Gorilla WebSocket库，用于WebSocket连接
@@ -17,27 +19,41 @@ var upGrader = websocket.Upgrader{
 	},
 }
 
-// RunSocekt 函数处理WebSocket连接
+// RunSocekt 函数处理WebSocket连接，连接的用户身份取自Auth中间件校验过的token
 func RunSocekt(c *gin.Context) {
-	user := c.Query("user") // 获取用户参数
+	user := c.GetString(constant.CONTEXT_USER_UUID) // 获取token中的用户uuid
 	if user == "" {
-		return // 如果没有用户参数，直接返回
+		return // 如果没有通过认证，直接返回
 	}
-	log.Logger.Info("newUser", zap.String("newUser", user)) // 记录新用户连接的日志
-	ws, err := upGrader.Upgrade(c.Writer, c.Request, nil)   // 将HTTP连接升级为WebSocket连接
+	if server.MyServer.Draining() { // 服务正在关闭，不再接受新的连接，客户端应稍后重连到其他节点
+		c.JSON(http.StatusServiceUnavailable, response.FailMsg("服务正在关闭，请稍后重连"))
+		return
+	}
+	// 设备id取自token，登录时绑定，同一设备重连时会替换旧连接
+	deviceId := c.GetString(constant.CONTEXT_DEVICE_ID)
+	deviceType := c.DefaultQuery("deviceType", constant.DEVICE_WEB)
+	if deviceType != constant.DEVICE_WEB && deviceType != constant.DEVICE_DESKTOP && deviceType != constant.DEVICE_MOBILE {
+		c.JSON(http.StatusOK, response.FailMsg("不支持的设备类型")) // 设备类型不合法，返回错误信息
+		return
+	}
+	// 群聊消息的帧格式，compact格式不需要为接收者重新序列化消息，适合大群
+	groupFrame := c.DefaultQuery("groupFrame", constant.GROUP_FRAME_FORWARD)
+	if groupFrame != constant.GROUP_FRAME_FORWARD && groupFrame != constant.GROUP_FRAME_COMPACT {
+		c.JSON(http.StatusOK, response.FailMsg("不支持的群消息格式"))
+		return
+	}
+
+	log.Logger.Info("newUser", zap.String("newUser", user), zap.String("device", deviceId)) // 记录新用户连接的日志
+	ws, err := upGrader.Upgrade(c.Writer, c.Request, nil)                                   // 将HTTP连接升级为WebSocket连接
 	if err != nil {
 		return // 如果升级失败，直接返回
 	}
 
-	// 创建一个新的客户端实例
-	client := &server.Client{
-		Name: user,              // 设置客户端的用户名
-		Conn: ws,                // WebSocket连接
-		Send: make(chan []byte), // 创建一个发送消息的通道
-	}
+	// 创建一个新的客户端实例，发送队列的长度取自配置
+	client := server.NewClient(user, deviceId, deviceType, groupFrame, ws)
 
 	// 将新客户端注册到服务器中
-	server.MyServer.Register <- client
+	server.MyServer.Register(client)
 	// 启动客户端的读写协程
 	go client.Read()
 	go client.Write()
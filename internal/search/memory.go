@@ -0,0 +1,176 @@
+package search
+
+import (
+	"sort"    // 引入排序包，用于按消息ID排序结果
+	"strings" // 引入字符串处理包
+	"sync"    // 引入同步包，用于保护索引
+	"time"    // 引入时间包，用于比较群组的加入时间
+	"unicode" // 引入unicode包，用于分词
+)
+
+// memoryIndex 结构体是进程内的倒排索引，适用于单机部署
+// 英文和数字按单词切分，中日韩文字按相邻两个字切分（单字同时保留），检索时再做一次子串匹配保证准确
+type memoryIndex struct {
+	mutex     sync.RWMutex
+	documents map[int32]Document            // 消息ID到消息的映射
+	postings  map[string]map[int32]struct{} // 词项到消息ID集合的倒排表
+}
+
+// NewMemoryIndex 函数创建一个空的进程内索引
+func NewMemoryIndex() Index {
+	return &memoryIndex{
+		documents: make(map[int32]Document),
+		postings:  make(map[string]map[int32]struct{}),
+	}
+}
+
+// Index 方法将消息加入倒排索引
+func (m *memoryIndex) Index(doc Document) error {
+	if doc.MessageId <= 0 || doc.Content == "" {
+		return nil
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.documents[doc.MessageId] = doc
+	for _, term := range tokenize(doc.Content) {
+		ids, ok := m.postings[term]
+		if !ok {
+			ids = make(map[int32]struct{})
+			m.postings[term] = ids
+		}
+		ids[doc.MessageId] = struct{}{}
+	}
+	return nil
+}
+
+// Remove 方法将消息从倒排索引中删除，词项对应的消息集合为空时一并删除
+func (m *memoryIndex) Remove(messageId int32) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	doc, ok := m.documents[messageId]
+	if !ok {
+		return nil
+	}
+	delete(m.documents, messageId)
+	for _, term := range tokenize(doc.Content) {
+		ids := m.postings[term]
+		delete(ids, messageId)
+		if len(ids) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	return nil
+}
+
+// Search 方法求所有词项倒排表的交集，过滤掉调用者无权查看的会话后按消息ID倒序返回
+func (m *memoryIndex) Search(query Query) ([]Hit, error) {
+	terms := tokenize(query.Keyword)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	keyword := strings.ToLower(query.Keyword)
+
+	groups := make(map[int32]time.Time, len(query.Groups))
+	for _, membership := range query.Groups {
+		groups[membership.GroupId] = membership.JoinedAt
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// 从最短的倒排表开始求交集
+	sort.Slice(terms, func(i, j int) bool {
+		return len(m.postings[terms[i]]) < len(m.postings[terms[j]])
+	})
+
+	var hits []Hit
+	for id := range m.postings[terms[0]] {
+		matched := true
+		for _, term := range terms[1:] {
+			if _, ok := m.postings[term][id]; !ok {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		doc := m.documents[id]
+		if !canView(doc, query.UserId, groups) || !strings.Contains(strings.ToLower(doc.Content), keyword) {
+			continue
+		}
+		hits = append(hits, Hit{MessageId: doc.MessageId, Content: doc.Content})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].MessageId > hits[j].MessageId
+	})
+	if query.Limit > 0 && len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// canView 函数判断调用者是否参与了消息所在的会话，群聊消息必须在调用者加入群组之后发送
+func canView(doc Document, userId int32, groups map[int32]time.Time) bool {
+	if doc.MessageType == 1 {
+		return doc.FromUserId == userId || doc.ToUserId == userId
+	}
+	if doc.MessageType == 2 {
+		joinedAt, ok := groups[doc.ToUserId]
+		return ok && !doc.CreatedAt.Before(joinedAt)
+	}
+	return false
+}
+
+// tokenize 函数对文本进行分词并去重
+func tokenize(text string) []string {
+	seen := make(map[string]struct{})
+	var terms []string
+	add := func(term string) {
+		if _, ok := seen[term]; ok {
+			return
+		}
+		seen[term] = struct{}{}
+		terms = append(terms, term)
+	}
+
+	var word []rune
+	var cjk []rune
+	flush := func() {
+		if len(word) > 0 {
+			add(string(word))
+			word = word[:0]
+		}
+		if len(cjk) == 1 {
+			add(string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			add(string(cjk[i : i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			if len(word) > 0 {
+				add(string(word))
+				word = word[:0]
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
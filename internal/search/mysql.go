@@ -0,0 +1,60 @@
+package search
+
+import (
+	"strings" // 引入字符串处理包，用于处理检索关键词
+
+	"gorm.io/gorm" // 引入GORM ORM库
+)
+
+// mysqlIndex 结构体使用MySQL的FULLTEXT索引（ngram分词）检索消息，索引由数据库自身维护
+type mysqlIndex struct {
+	db *gorm.DB
+}
+
+// NewMySQLIndex 函数创建一个基于MySQL全文索引的检索实现
+func NewMySQLIndex(db *gorm.DB) Index {
+	return &mysqlIndex{db: db}
+}
+
+// Index 方法无需处理，消息写入messages表后由MySQL维护全文索引
+func (m *mysqlIndex) Index(doc Document) error {
+	return nil
+}
+
+// Remove 方法无需处理，消息内容修改后由MySQL维护全文索引
+func (m *mysqlIndex) Remove(messageId int32) error {
+	return nil
+}
+
+// Search 方法使用布尔模式的短语检索，并限定在调用者参与的会话内
+func (m *mysqlIndex) Search(query Query) ([]Hit, error) {
+	// 去掉布尔模式中的特殊字符，整体作为短语检索
+	keyword := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`"+-<>()~*@`, r) {
+			return ' '
+		}
+		return r
+	}, query.Keyword)
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, nil
+	}
+
+	// 每个群组只检索加入之后的消息，没有群组时群聊条件恒为假
+	groupCondition := "FALSE"
+	args := []interface{}{`"` + keyword + `"`, query.UserId, query.UserId}
+	if len(query.Groups) > 0 {
+		conditions := make([]string, 0, len(query.Groups))
+		for _, membership := range query.Groups {
+			conditions = append(conditions, "(m.to_user_id = ? AND m.created_at >= ?)")
+			args = append(args, membership.GroupId, membership.JoinedAt)
+		}
+		groupCondition = strings.Join(conditions, " OR ")
+	}
+	args = append(args, query.Limit)
+
+	var hits []Hit
+	err := m.db.Raw("SELECT m.id AS message_id, m.content FROM messages AS m WHERE MATCH(m.content) AGAINST(? IN BOOLEAN MODE) AND m.content_type = 1 AND ((m.message_type = 1 AND (m.from_user_id = ? OR m.to_user_id = ?)) OR (m.message_type = 2 AND ("+groupCondition+"))) ORDER BY m.id DESC LIMIT ?",
+		args...).Scan(&hits).Error
+	return hits, err
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"html"    // 引入html包，用于转义高亮片段中的用户内容
+	"strings" // 引入字符串处理包
+	"time"    // 引入时间包
+)
+
+// Document 结构体表示一条需要被索引的消息
+type Document struct {
+	MessageId   int32     // 消息ID
+	MessageType int32     // 消息类型，1.单聊 2.群聊
+	FromUserId  int32     // 发送者的用户ID
+	ToUserId    int32     // 单聊为接收者的用户ID，群聊为群组ID
+	Content     string    // 文本消息内容
+	CreatedAt   time.Time // 消息的创建时间
+}
+
+// Query 结构体表示一次检索请求，检索范围限定在调用者参与的会话内
+type Query struct {
+	Keyword string       // 检索关键词
+	UserId  int32        // 调用者的用户ID，只能检索自己发送或接收的单聊消息
+	Groups  []Membership // 调用者所在的群组，只能检索这些群组内加入之后的群聊消息
+	Limit   int          // 最多返回的结果数
+}
+
+// Membership 结构体表示调用者所在的一个群组及其加入时间
+type Membership struct {
+	GroupId  int32     // 群组ID
+	JoinedAt time.Time // 加入群组的时间，之前的群聊消息不能检索，零值表示不限制
+}
+
+// Hit 结构体表示一条检索结果
+type Hit struct {
+	MessageId int32  // 命中的消息ID
+	Content   string // 命中的消息内容，用于生成片段和高亮
+}
+
+// Index 接口定义了消息全文检索索引，不同的实现可以按部署方式替换
+type Index interface {
+	Index(doc Document) error          // 将消息加入索引，由数据库自身维护索引的实现可以忽略
+	Remove(messageId int32) error      // 将消息移出索引，消息被撤回或编辑时调用，由数据库自身维护索引的实现可以忽略
+	Search(query Query) ([]Hit, error) // 检索消息，结果按消息ID倒序排列
+}
+
+// Highlight 函数返回content中第一个关键词附近的片段，以及用<em>标签标出所有关键词的高亮片段
+// width为关键词前后保留的字符数，高亮片段中的用户内容已经过HTML转义
+func Highlight(content, keyword string, width int) (snippet string, highlighted string) {
+	runes := []rune(content)
+	lowerRunes := []rune(strings.ToLower(content))
+	keywordRunes := []rune(strings.ToLower(keyword))
+	if len(keywordRunes) == 0 || len(lowerRunes) != len(runes) {
+		return content, html.EscapeString(content)
+	}
+
+	index := indexRunes(lowerRunes, keywordRunes, 0)
+	if index < 0 {
+		return content, html.EscapeString(content)
+	}
+
+	// 截取关键词前后width个字符作为片段
+	start := index - width
+	if start < 0 {
+		start = 0
+	}
+	end := index + len(keywordRunes) + width
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var plain, marked strings.Builder
+	if start > 0 {
+		plain.WriteString("...")
+		marked.WriteString("...")
+	}
+	plain.WriteString(string(runes[start:end]))
+
+	for position := start; position < end; {
+		next := indexRunes(lowerRunes[:end], keywordRunes, position)
+		if next < 0 {
+			marked.WriteString(html.EscapeString(string(runes[position:end])))
+			break
+		}
+		marked.WriteString(html.EscapeString(string(runes[position:next])))
+		marked.WriteString("<em>" + html.EscapeString(string(runes[next:next+len(keywordRunes)])) + "</em>")
+		position = next + len(keywordRunes)
+	}
+
+	if end < len(runes) {
+		plain.WriteString("...")
+		marked.WriteString("...")
+	}
+	return plain.String(), marked.String()
+}
+
+// indexRunes 函数从from位置开始查找sub在s中第一次出现的位置，找不到返回-1
+func indexRunes(s, sub []rune, from int) int {
+	for i := from; i+len(sub) <= len(s); i++ {
+		matched := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"chat-room/config"              // 引入配置包，用于读取消息通道配置
+	"chat-room/internal/bus"        // 引入消息总线包
+	"chat-room/internal/dao/pool"   // 引入连接池，用于获取Redis客户端
+	"chat-room/internal/kafka"      // 引入Kafka包，用于配置生产者
+	"chat-room/internal/service"    // 引入服务层，用于清理节点路由记录
+	"chat-room/pkg/common/constant" // 引入常量包，定义了消息通道类型
+)
+
+// messageBus 是节点之间传递消息的总线，由InitMessageBus根据配置创建
+var messageBus = bus.NewChannelBus()
+
+// InitMessageBus 函数根据配置创建消息总线，并订阅需要本节点处理的消息
+func InitMessageBus() error {
+	channel := config.GetConfig().MsgChannelType
+	if trackNodes() { // 清理本节点上次退出时遗留的用户路由记录
+		if err := service.NodeService.ClearNode(channel.NodeId); err != nil {
+			return err
+		}
+	}
+	switch channel.ChannelType {
+	case constant.KAFKA:
+		options := kafka.ProducerOptions{
+			Acks:        channel.KafkaAcks,
+			Retries:     channel.KafkaRetries,
+			Idempotent:  channel.KafkaIdempotent,
+			Compression: channel.KafkaCompression,
+		}
+		kafkaBus, err := bus.NewKafkaBus(channel.KafkaHosts, channel.KafkaTopic, channel.KafkaGroup, channel.NodeId, channel.KafkaPartitions, options, routeKafkaMsg)
+		if err != nil {
+			return err
+		}
+		messageBus = kafkaBus
+	case constant.REDIS:
+		messageBus = bus.NewRedisBus(pool.GetRedis(), channel.RedisChannel)
+	default:
+		messageBus = bus.NewChannelBus()
+	}
+	return messageBus.Subscribe(ConsumerMsg)
+}
+
+// trackNodes 函数判断是否需要记录用户所在的节点，多节点部署时用于路由消息和统计集群中的设备数
+func trackNodes() bool {
+	return config.GetConfig().MsgChannelType.ChannelType != constant.GO_CHANNEL
+}
+
+// CloseMessageBus 函数关闭消息总线
+func CloseMessageBus() error {
+	return messageBus.Close()
+}
@@ -1,11 +1,13 @@
 package server
 
 import (
-	"chat-room/config"              // 引入配置包，用于获取配置信息
-	"chat-room/internal/kafka"      // 引入Kafka包，用于处理Kafka消息队列
+	"chat-room/config"              // 引入配置包，用于读取写超时
+	"chat-room/internal/service"    // 引入服务层，用于查询发送者信息和保存投递游标
 	"chat-room/pkg/common/constant" // 引入常量包，定义项目中的常量
 	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
 	"chat-room/pkg/protocol"        // 引入协议包，用于处理消息的协议格式
+	"sync"                          // 引入同步包，用于保护发送通道的关闭
+	"time"                          // 引入时间包，用于生成服务端时间戳
 
 	"github.com/gogo/protobuf/proto" // 引入Protobuf库，用于序列化和反序列化消息
 	"github.com/gorilla/websocket"   // 引入Gorilla WebSocket库，用于处理WebSocket连接
@@ -13,31 +15,59 @@ import (
 
 // Client 结构体表示一个WebSocket连接客户端
 type Client struct {
-	Conn *websocket.Conn // WebSocket连接实例
-	Name string          // 客户端的名称（通常是用户名）
-	Send chan []byte     // 发送消息的通道
+	Conn        *websocket.Conn                   // WebSocket连接实例
+	Name        string                            // 客户端的名称（用户uuid）
+	DeviceId    string                            // 设备id，同一用户的每个设备各自维护一个连接
+	DeviceType  string                            // 设备类型：web、desktop或mobile
+	GroupFrame  string                            // 群聊消息的帧格式：forward或compact
+	ConnectedAt time.Time                         // 建立连接的时间
+	send        chan *outbound                    // 有缓冲的发送队列，由Write协程写入连接
+	cursors     map[service.ConversationKey]int64 // 本次连接中各会话已写入的最后一条消息ID，只在Write协程中访问，退出时保存到数据库
+	mutex       sync.Mutex                        // 保护以下状态，避免向已关闭的发送队列写入
+	closed      bool                              // 发送队列是否已关闭
+	spilled     bool                              // 队列曾经写满，带游标的消息转为离线补发
+	slow        bool                              // 已因队列写满被断开
+}
+
+// User 方法返回客户端的用户uuid，用于登记到注册表
+func (c *Client) User() string {
+	return c.Name
+}
+
+// Device 方法返回客户端的设备id，用于登记到注册表
+func (c *Client) Device() string {
+	return c.DeviceId
 }
 
 // Read 方法用于从WebSocket连接读取消息
 func (c *Client) Read() {
 	defer func() {
-		MyServer.Ungister <- c // 当连接关闭时，将客户端从服务器的客户端列表中注销
+		MyServer.Unregister(c) // 当连接关闭时，将客户端从服务器的客户端列表中注销
 		c.Conn.Close()         // 关闭连接
 	}()
 
+	// 超过读超时没有收到pong或任何消息时ReadMessage返回错误，断开的TCP连接不会一直留在注册表中
+	c.extendReadDeadline()
+	c.Conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+
 	for {
-		c.Conn.PongHandler()                    // 处理WebSocket的Pong消息，保持连接活跃
 		_, message, err := c.Conn.ReadMessage() // 读取消息
 		if err != nil {
-			// 如果读取消息失败，记录错误日志并关闭连接
+			// 如果读取消息失败，记录错误日志，由defer注销客户端并关闭连接
 			log.Logger.Error("client read message error", log.Any("client read message error", err.Error()))
-			MyServer.Ungister <- c // 将客户端从服务器的客户端列表中注销
-			c.Conn.Close()         // 关闭连接
 			break
 		}
+		c.extendReadDeadline() // 收到任何消息（包括旧客户端的heatbeat心跳）都说明连接仍然存活
 
-		msg := &protocol.Message{}    // 创建一个空的Message对象
-		proto.Unmarshal(message, msg) // 反序列化从客户端接收到的消息
+		msg := &protocol.Message{}                            // 创建一个空的Message对象
+		if err := proto.Unmarshal(message, msg); err != nil { // 反序列化从客户端接收到的消息
+			log.Logger.Error("client unmarshal message error", log.Any("client unmarshal message error", err.Error()))
+			c.sendError("消息格式错误")
+			continue
+		}
 
 		// 处理心跳消息（pong响应）
 		if msg.Type == constant.HEAT_BEAT {
@@ -45,29 +75,302 @@ func (c *Client) Read() {
 				Content: constant.PONG,      // 响应内容设置为PONG
 				Type:    constant.HEAT_BEAT, // 消息类型为心跳
 			}
-			pongByte, err2 := proto.Marshal(pong) // 将响应消息序列化为字节数组
-			if nil != err2 {
-				log.Logger.Error("client marshal message error", log.Any("client marshal message error", err2.Error()))
-			}
-			c.Conn.WriteMessage(websocket.BinaryMessage, pongByte) // 将响应消息写回客户端
+			c.sendFrame(pong) // 将响应消息放入发送队列，连接只由Write协程写入
 		} else {
-			// 如果消息不是心跳消息，则根据配置决定使用Kafka还是直接广播
-			if config.GetConfig().MsgChannelType.ChannelType == constant.KAFKA {
-				kafka.Send(message) // 通过Kafka发送消息
-			} else {
-				MyServer.Broadcast <- message // 通过服务器的广播通道广播消息
+			// 消息发送者必须是当前连接认证过的用户，防止客户端冒充他人发送消息
+			if msg.From != c.Name {
+				log.Logger.Warn("client spoof message sender", log.String("client", c.Name), log.String("from", msg.From))
+				c.sendError("消息发送者与当前登录用户不一致")
+				continue
+			}
+
+			// 群组系统消息、好友通知、消息撤回和编辑通知以及远程下线只能由服务端发布
+			if msg.Type == constant.SYSTEM || msg.Type == constant.FRIEND || msg.Type == constant.RECALL || msg.Type == constant.EDIT ||
+				msg.Type == constant.LOGOUT {
+				c.sendError("不支持的消息类型")
+				continue
 			}
+
+			// 发送者的用户名和头像以服务端数据为准
+			fromUser := service.UserService.GetUserDetails(c.Name)
+			msg.FromUsername = fromUser.Username
+			msg.Avatar = fromUser.Avatar
+			msg.FromDeviceId = c.DeviceId                                   // 记录发送设备，用于同步到发送者的其他设备
+			msg.Timestamp = time.Now().UnixNano() / int64(time.Millisecond) // 设置服务端时间戳
+
+			// 正在输入：不持久化，直接转发给会话的另一方或群组其他成员，不是群组成员时丢弃
+			if msg.Type == constant.TYPING {
+				if msg.MessageType == constant.MESSAGE_TYPE_GROUP && !service.GroupService.IsGroupMember(msg.To, c.Name) {
+					c.sendError("不是群组成员")
+					continue
+				}
+				if typingByte, err := proto.Marshal(msg); err == nil {
+					publish(msg, typingByte, nil)
+				}
+				continue
+			}
+
+			// 在线状态：客户端只能在在线和离开之间切换，离线由服务端在连接断开时发布
+			if msg.Type == constant.PRESENCE {
+				if msg.Content != constant.PRESENCE_ONLINE && msg.Content != constant.PRESENCE_AWAY {
+					c.sendError("不支持的在线状态")
+					continue
+				}
+				publishPresence(c.Name, msg.Content)
+				continue
+			}
+
+			// 已读回执：保存已读位置后转发给会话的另一方或群组其他成员
+			if msg.Type == constant.READ {
+				if err := service.MessageService.MarkRead(c.Name, msg.MessageType, msg.To, msg.Id); err != nil {
+					c.sendError(err.Error())
+					continue
+				}
+				if readByte, err := proto.Marshal(msg); err == nil {
+					publish(msg, readByte, nil)
+				}
+				continue
+			}
+
+			c.sendMessage(msg)
 		}
 	}
 }
 
-// Write 方法用于向WebSocket连接发送消息
+// sendMessage 方法保存并发布消息：文字、文件、图片、音视频消息需要先持久化，由服务端生成消息id，成功后回复ACK，失败回复NACK
+// 持久化过程登记为在途保存，关闭服务时会等待其完成
+func (c *Client) sendMessage(msg *protocol.Message) {
+	persist := msg.To != "" && msg.ContentType >= constant.TEXT && msg.ContentType <= constant.VIDEO
+	// 群消息在保存文件和转发前检查发送者是否被禁言，不持久化的群消息同样受限
+	if msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+		if err := service.GroupService.CheckSpeak(msg.To, c.Name); err != nil {
+			c.sendNack(msg, err.Error())
+			return
+		}
+	}
+	// 被对方屏蔽时单聊消息不保存也不转发
+	if msg.MessageType == constant.MESSAGE_TYPE_USER && msg.To != "" && service.UserService.IsBlocked(msg.To, c.Name) {
+		c.sendNack(msg, "消息已被对方拒收")
+		return
+	}
+	if persist {
+		MyServer.beginSave()
+		defer MyServer.endSave()
+
+		saved, saveErr := saveMessage(msg)
+		if saveErr != nil {
+			c.sendNack(msg, saveErr.Error())
+			return
+		}
+		msg.Id = int64(saved.ID)
+		msg.Timestamp = saved.CreatedAt.UnixNano() / int64(time.Millisecond)
+	}
+
+	message, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("client marshal message error", log.Any("client marshal message error", err.Error()))
+		return
+	}
+
+	if !persist {
+		publish(msg, message, nil)
+		return
+	}
+	// 持久化的消息在消息总线确认发布后回复ACK；发布失败时回复带消息id的NACK，消息已保存，接收者会通过离线消息补发收到
+	publish(msg, message, func(err error) {
+		if err != nil {
+			c.sendNack(msg, "消息投递失败："+err.Error())
+			return
+		}
+		c.sendAck(msg)
+	})
+}
+
+// publish 函数将消息发布到消息总线，由Server路由给接收者，msg为message反序列化后的内容
+// done在发布成功或最终失败时调用，可能在其他goroutine中执行，可以为nil
+func publish(msg *protocol.Message, message []byte, done func(err error)) {
+	// 同一会话的消息使用相同的key，保证有序
+	messageBus.Publish(routingKey(msg), message, func(err error) {
+		if err != nil {
+			log.Logger.Error("publish message error", log.String("publish message error", err.Error()))
+		}
+		if done != nil {
+			done(err)
+		}
+	})
+}
+
+// sendAck 方法在消息持久化成功后向发送者回复ACK帧，携带服务端消息id、时间戳和客户端消息id
+func (c *Client) sendAck(msg *protocol.Message) {
+	c.sendFrame(&protocol.Message{
+		Type:        constant.ACK,
+		To:          msg.To,
+		MessageType: msg.MessageType,
+		Id:          msg.Id,
+		Timestamp:   msg.Timestamp,
+		ClientMsgId: msg.ClientMsgId,
+	})
+}
+
+// sendNack 方法在消息持久化或投递失败时向发送者回复NACK帧，Content为失败原因，Id不为0时表示消息已保存
+func (c *Client) sendNack(msg *protocol.Message, reason string) {
+	c.sendFrame(&protocol.Message{
+		Type:        constant.NACK,
+		To:          msg.To,
+		MessageType: msg.MessageType,
+		Id:          msg.Id,
+		Content:     reason,
+		ClientMsgId: msg.ClientMsgId,
+	})
+}
+
+// markDelivered 方法记录某个会话中已写入连接的最后一条消息ID，只在Write协程中调用
+func (c *Client) markDelivered(messageType int32, conversationUuid string, messageId int64) {
+	if messageId <= 0 { // 未持久化的消息没有ID，不需要记录游标
+		return
+	}
+	if c.cursors == nil {
+		c.cursors = make(map[service.ConversationKey]int64)
+	}
+	key := service.ConversationKey{MessageType: messageType, Uuid: conversationUuid}
+	if messageId > c.cursors[key] {
+		c.cursors[key] = messageId
+	}
+}
+
+// sendError 方法向客户端发送错误帧，Type为error，Content为错误原因
+func (c *Client) sendError(reason string) {
+	c.sendFrame(&protocol.Message{
+		Type:    constant.ERROR,
+		Content: reason,
+	})
+}
+
+// sendFrame 方法将服务端生成的控制帧序列化后放入发送队列，连接已注销时丢弃
+// 消息总线的发布结果可能在其他goroutine中回调，入队和close互斥
+func (c *Client) sendFrame(frame *protocol.Message) {
+	frameByte, err := proto.Marshal(frame)
+	if err != nil {
+		log.Logger.Error("client marshal frame error", log.Any("client marshal frame error", err.Error()))
+		return
+	}
+	c.enqueue(frameByte)
+}
+
+// close 方法关闭发送队列，只在Server注销客户端时调用
+func (c *Client) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+	close(c.send)
+}
+
+// Write 方法用于向WebSocket连接发送消息，每批写入设置写超时，写入失败时关闭连接
+// 开始写入发送队列之前先补发离线消息，补发期间路由到该连接的新消息在队列中等待，保证先写入较早的消息
+// 按pingInterval发送ping控制帧，客户端回复的pong会延长Read的读超时
+// 退出时保存本次连接的投递游标，游标只记录真正写入连接的消息
 func (c *Client) Write() {
+	MyServer.beginWrite()
+	defer MyServer.endWrite()
 	defer func() {
-		c.Conn.Close() // 当写操作完成后，关闭连接
+		c.Conn.Close()                                                    // 当写操作完成后，关闭连接
+		service.MessageService.SaveCursors(c.Name, c.DeviceId, c.cursors) // 保存本次连接的投递游标
 	}()
 
-	for message := range c.Send { // 读取发送通道中的消息
-		c.Conn.WriteMessage(websocket.BinaryMessage, message) // 将消息发送给客户端
+	writeTimeout := time.Duration(config.GetConfig().Server.WriteTimeout) * time.Second
+	if !c.writeOfflineMessages(writeTimeout) {
+		return
+	}
+
+	ticker := time.NewTicker(pingInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case item, ok := <-c.send: // 读取发送队列中的消息
+			if !ok { // 发送队列已关闭，客户端已注销
+				return
+			}
+			// 一次唤醒连续写入队列中已有的消息，最多WRITE_BATCH_SIZE条，整批共用一个写超时
+			if writeTimeout > 0 {
+				c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			}
+			for written := 1; ; written++ {
+				if err := c.writeItem(item); err != nil { // 将消息发送给客户端
+					log.Logger.Error("client write message error", log.String("client", c.Name), log.Any("client write message error", err.Error()))
+					return
+				}
+				c.markDelivered(item.messageType, item.conversation, item.id)
+				if written >= WRITE_BATCH_SIZE || len(c.send) == 0 {
+					break
+				}
+				if item, ok = <-c.send; !ok { // 只有Write协程读取队列，队列不为空时不会阻塞
+					return
+				}
+			}
+
+			// 队列清空后退出离线补发状态，保存游标后从数据库补发离线补发状态期间的消息
+			if len(c.send) == 0 && c.resume() {
+				service.MessageService.SaveCursors(c.Name, c.DeviceId, c.cursors)
+				if !c.writeOfflineMessages(writeTimeout) {
+					return
+				}
+			}
+
+		case <-ticker.C: // 定时发送ping控制帧
+			if writeTimeout > 0 {
+				c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			}
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Logger.Error("client write ping error", log.String("client", c.Name), log.Any("client write ping error", err.Error()))
+				return
+			}
+		}
+	}
+}
+
+// writeItem 方法向连接写入发送队列中的一条消息，有预先编码的帧时直接写入，只在Write协程中调用
+func (c *Client) writeItem(item *outbound) error {
+	if item.prepared != nil {
+		return c.Conn.WritePreparedMessage(item.prepared)
+	}
+	return c.Conn.WriteMessage(websocket.BinaryMessage, item.data)
+}
+
+// writeMessage 方法设置写超时后向连接写入一条消息，只在Write协程中调用
+func (c *Client) writeMessage(writeTimeout time.Duration, data []byte) error {
+	if writeTimeout > 0 {
+		c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	}
+	return c.Conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// writeOfflineMessages 方法按顺序补发用户上次投递游标之后的消息，直接写入连接并推进游标，只在Write协程中调用
+// 补发的消息不经过发送队列，积压再多也不会触发慢消费者策略；写入失败时返回false
+func (c *Client) writeOfflineMessages(writeTimeout time.Duration) bool {
+	messages, err := service.MessageService.GetOfflineMessages(c.Name, c.DeviceId)
+	if err != nil {
+		log.Logger.Error("get offline messages error", log.String("get offline messages error", err.Error()))
+		return true
+	}
+
+	for i := range messages {
+		// 单聊消息的会话为发送者，群聊消息的From字段为群组uuid
+		messageType, conversation := messages[i].MessageType, messages[i].From
+		if messageType == constant.MESSAGE_TYPE_GROUP && c.GroupFrame == constant.GROUP_FRAME_COMPACT {
+			// compact格式与在线转发一致：From为发送者uuid，To为群组uuid
+			messages[i].From, messages[i].To = messages[i].To, messages[i].From
+		}
+		msgByte, err := proto.Marshal(&messages[i])
+		if err != nil {
+			continue
+		}
+		if err := c.writeMessage(writeTimeout, msgByte); err != nil {
+			log.Logger.Error("client write offline message error", log.String("client", c.Name), log.Any("client write offline message error", err.Error()))
+			return false
+		}
+		c.markDelivered(messageType, conversation, messages[i].Id)
 	}
+	return true
 }
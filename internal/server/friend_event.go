@@ -0,0 +1,42 @@
+package server
+
+import (
+	"chat-room/internal/service"    // 引入服务层，用于查询操作者信息
+	"chat-room/pkg/common/constant" // 引入常量包，定义了好友通知类型
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"chat-room/pkg/protocol"        // 引入协议包，用于构造好友通知
+	"time"                          // 引入时间包，用于生成服务端时间戳
+
+	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于序列化消息
+)
+
+// PublishFriendEvent 函数发布好友通知，好友请求发送、通过、拒绝、取消以及删除好友后由接口调用
+// operatorUuid为操作者，otherUuid为另一方，requestId为好友请求id，删除好友时为0
+func PublishFriendEvent(operatorUuid, otherUuid string, requestId int32, event string) {
+	msg := &protocol.Message{
+		Type:         constant.FRIEND,
+		MessageType:  constant.MESSAGE_TYPE_USER,
+		From:         operatorUuid,
+		FromUsername: service.UserService.GetUserDetails(operatorUuid).Username,
+		To:           otherUuid,
+		Content:      event,
+		Id:           int64(requestId),
+		Timestamp:    time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	msgByte, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("marshal friend event error", log.String("marshal friend event error", err.Error()))
+		return
+	}
+	publish(msg, msgByte, nil)
+}
+
+// sendFriendEvent 函数把好友通知发送给另一方的所有设备，并同步到操作者的所有设备
+func sendFriendEvent(message []byte, msg *protocol.Message, s *Server) {
+	for _, client := range s.devices(msg.To) {
+		client.enqueue(message)
+	}
+	for _, client := range s.devices(msg.From) {
+		client.enqueue(message)
+	}
+}
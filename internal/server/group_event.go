@@ -0,0 +1,75 @@
+package server
+
+import (
+	"chat-room/config"              // 引入配置包，用于读取慢消费者策略
+	"chat-room/internal/hub"        // 引入hub包，用于批量遍历群成员的连接
+	"chat-room/internal/service"    // 引入服务层，用于查询群成员和操作者信息
+	"chat-room/pkg/common/constant" // 引入常量包，定义了系统消息类型
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"chat-room/pkg/protocol"        // 引入协议包，用于构造系统消息
+	"time"                          // 引入时间包，用于生成服务端时间戳
+
+	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于序列化消息
+)
+
+// PublishGroupEvent 函数向群组发布系统消息，群组成员变化、角色变化或解散后由接口调用
+// operatorUuid为操作者，target为涉及的成员，已离开群组的target同样会收到这条消息
+func PublishGroupEvent(groupUuid, operatorUuid, target, event string) {
+	publishGroupEvent(groupUuid, operatorUuid, target, event, 0)
+}
+
+// PublishJoinRequestEvent 函数发布加群申请相关的系统消息，Id为申请id
+// 提交和拒绝申请只通知群主、管理员和申请人，通过申请作为join事件通知群组所有成员
+func PublishJoinRequestEvent(groupUuid, operatorUuid, applicantUuid string, requestId int32, event string) {
+	publishGroupEvent(groupUuid, operatorUuid, applicantUuid, event, int64(requestId))
+}
+
+func publishGroupEvent(groupUuid, operatorUuid, target, event string, id int64) {
+	msg := &protocol.Message{
+		Type:         constant.SYSTEM,
+		MessageType:  constant.MESSAGE_TYPE_GROUP,
+		From:         operatorUuid,
+		FromUsername: service.UserService.GetUserDetails(operatorUuid).Username,
+		To:           groupUuid,
+		Target:       target,
+		Content:      event,
+		Id:           id,
+		Timestamp:    time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	msgByte, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("marshal group event error", log.String("marshal group event error", err.Error()))
+		return
+	}
+	publish(msg, msgByte, nil)
+}
+
+// sendGroupEvent 函数把群组系统消息发送给群组当前所有成员的所有设备，以及不在群组中的target
+// 加群申请的提交和拒绝只发送给群主、管理员和target
+func sendGroupEvent(message []byte, msg *protocol.Message, s *Server) {
+	var members []string
+	if msg.Content == constant.GROUP_EVENT_REQUEST || msg.Content == constant.GROUP_EVENT_REJECT {
+		members = service.GroupService.GetGroupAdminUuids(msg.To)
+	} else {
+		members = service.GroupService.GetGroupMemberUuids(msg.To)
+	}
+	if msg.Target != "" && !containsUser(members, msg.Target) {
+		members = append(members[:len(members):len(members)], msg.Target) // 不修改缓存返回的切片
+	}
+
+	item := &outbound{data: message} // 所有成员共享同一个outbound
+	policy := config.GetConfig().Server.SlowConsumerPolicy
+	s.clients.ForEach(members, func(session hub.Session) {
+		session.(*Client).push(item, policy)
+	})
+}
+
+// containsUser 函数判断users中是否包含userUuid
+func containsUser(users []string, userUuid string) bool {
+	for _, user := range users {
+		if user == userUuid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"chat-room/config" // 引入配置包，用于读取心跳配置
+	"time"             // 引入时间包，用于计算心跳间隔和读超时
+)
+
+const (
+	DEFAULT_PING_INTERVAL = 30 * time.Second // 没有配置时服务端发送ping的间隔
+	DEFAULT_PONG_TIMEOUT  = 60 * time.Second // 没有配置时等待客户端任意数据（pong或消息）的超时时间
+)
+
+// pingInterval 函数返回服务端发送ping控制帧的间隔
+func pingInterval() time.Duration {
+	if interval := config.GetConfig().Server.PingInterval; interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	return DEFAULT_PING_INTERVAL
+}
+
+// pongTimeout 函数返回读超时，超过该时间没有收到pong或任何消息时认为连接已断开，应大于pingInterval
+func pongTimeout() time.Duration {
+	if timeout := config.GetConfig().Server.PongTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return DEFAULT_PONG_TIMEOUT
+}
+
+// extendReadDeadline 方法在收到pong或客户端消息后延长读超时
+func (c *Client) extendReadDeadline() {
+	c.Conn.SetReadDeadline(time.Now().Add(pongTimeout()))
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"chat-room/config"              // 引入配置包，用于读取慢消费者策略
+	"chat-room/internal/hub"        // 引入hub包，用于批量遍历群成员的连接
+	"chat-room/internal/service"    // 引入服务层，用于查询群成员
+	"chat-room/pkg/common/constant" // 引入常量包，定义了会话类型
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"chat-room/pkg/protocol"        // 引入协议包
+
+	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于序列化消息
+)
+
+// PublishMessageEvent 函数发布消息撤回或编辑通知，Id为被撤回或编辑的消息id，编辑通知的Content为编辑后的内容
+func PublishMessageEvent(msg *protocol.Message) {
+	msgByte, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("marshal message event error", log.String("marshal message event error", err.Error()))
+		return
+	}
+	publish(msg, msgByte, nil)
+}
+
+// sendMessageEvent 函数投递消息撤回或编辑通知，单聊发送给双方的所有设备，群聊发送给群组当前所有成员的所有设备
+func sendMessageEvent(message []byte, msg *protocol.Message, s *Server) {
+	if msg.MessageType == constant.MESSAGE_TYPE_USER {
+		sendFriendEvent(message, msg, s)
+		return
+	}
+
+	item := &outbound{data: message} // 所有成员共享同一个outbound
+	policy := config.GetConfig().Server.SlowConsumerPolicy
+	s.clients.ForEach(service.GroupService.GetGroupMemberUuids(msg.To), func(session hub.Session) {
+		session.(*Client).push(item, policy)
+	})
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"chat-room/internal/service"    // 引入服务层，用于查询好友列表
+	"chat-room/pkg/common/constant" // 引入常量包，定义了在线状态
+	"chat-room/pkg/common/response" // 引入通用响应包，定义了在线状态的响应
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"chat-room/pkg/protocol"        // 引入协议包，用于构造在线状态消息
+	"time"                          // 引入时间包，用于记录最后在线时间
+
+	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于序列化消息
+)
+
+// presence 结构体记录用户的在线状态和最后在线时间
+type presence struct {
+	status   string // 在线状态：online、away或offline
+	lastSeen int64  // 最后在线时间，单位毫秒
+}
+
+// GetPresence 方法获取用户的在线状态，没有记录的用户视为离线
+func (s *Server) GetPresence(userUuid string) response.PresenceResponse {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.presences[userUuid]
+	if !ok {
+		return response.PresenceResponse{Uuid: userUuid, Status: constant.PRESENCE_OFFLINE}
+	}
+	return response.PresenceResponse{Uuid: userUuid, Status: p.status, LastSeen: p.lastSeen}
+}
+
+// setPresence 方法更新用户的在线状态，状态没有变化时返回false
+func (s *Server) setPresence(userUuid, status string, lastSeen int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.presences[userUuid]
+	if ok && p.status == status {
+		p.lastSeen = lastSeen
+		return false
+	}
+	s.presences[userUuid] = &presence{status: status, lastSeen: lastSeen}
+	return true
+}
+
+// publishPresence 函数发布用户的在线状态变化，所有节点收到后更新状态并通知本节点在线的好友
+// 不能在路由worker中调用，单机总线同步投递时会向worker自身的队列发送消息
+func publishPresence(userUuid, status string) {
+	msg := &protocol.Message{
+		Type:      constant.PRESENCE,
+		From:      userUuid,
+		Content:   status,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	msgByte, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("marshal presence error", log.String("marshal presence error", err.Error()))
+		return
+	}
+	publish(msg, msgByte, nil)
+}
+
+// handlePresence 函数处理在线状态消息：更新状态，并转发给在线的好友
+func handlePresence(message []byte, msg *protocol.Message, s *Server) {
+	if !s.setPresence(msg.From, msg.Content, msg.Timestamp) {
+		return
+	}
+	for _, friendUuid := range service.UserService.GetFriendUuids(msg.From) {
+		for _, client := range s.devices(friendUuid) {
+			client.enqueue(message)
+		}
+	}
+}
+
+// sendFriendPresences 函数在用户上线时，推送其好友当前的在线状态
+func sendFriendPresences(client *Client, s *Server) {
+	for _, friendUuid := range service.UserService.GetFriendUuids(client.Name) {
+		p := s.GetPresence(friendUuid)
+		if p.Status == constant.PRESENCE_OFFLINE && p.LastSeen == 0 {
+			continue
+		}
+		client.sendFrame(&protocol.Message{
+			Type:      constant.PRESENCE,
+			From:      friendUuid,
+			Content:   p.Status,
+			Timestamp: p.LastSeen,
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"chat-room/config"              // 引入配置包，用于读取发送队列配置
+	"chat-room/internal/hub"        // 引入hub包，用于遍历连接注册表
+	"chat-room/pkg/common/constant" // 引入常量包，定义了慢消费者策略
+	"expvar"                        // 引入expvar包，用于暴露发送队列指标
+	"time"                          // 引入时间包，用于记录连接时间
+
+	"github.com/gorilla/websocket" // 引入Gorilla WebSocket库
+)
+
+const (
+	DEFAULT_SEND_QUEUE_SIZE = 256  // 没有配置时每个连接的发送队列长度
+	CLOSE_SLOW_CONSUMER     = 4002 // 慢消费者被断开时关闭帧使用的状态码
+	WRITE_BATCH_SIZE        = 64   // Write协程一次唤醒最多连续写入的消息数
+)
+
+// 发送队列指标，通过/debug/vars查看
+var (
+	sendDropped     = expvar.NewInt("client_send_dropped")     // 队列已满被丢弃的消息数
+	sendSpilled     = expvar.NewInt("client_send_spilled")     // 队列已满转为离线补发的消息数
+	slowDisconnects = expvar.NewInt("client_slow_disconnects") // 因队列已满被断开的连接数
+)
+
+func init() {
+	expvar.Publish("client_send_queue", expvar.Func(func() interface{} {
+		return MyServer.queueStats()
+	}))
+}
+
+// outbound 是发送队列中的一条消息，带会话信息的消息写入连接成功后才推进投递游标
+// 群消息扇出时同一条outbound放入所有成员的发送队列，入队后不能再修改
+type outbound struct {
+	data         []byte                     // 序列化后的消息
+	prepared     *websocket.PreparedMessage // 预先编码的WebSocket帧，不为nil时代替data写入，所有连接共享一份编码结果
+	messageType  int32                      // 会话类型，id为0时不记录游标
+	conversation string                     // 会话uuid：单聊为对方uuid，群聊为群组uuid
+	id           int64                      // 消息id
+}
+
+// NewClient 函数创建客户端，发送队列的长度取自配置
+func NewClient(name, deviceId, deviceType, groupFrame string, conn *websocket.Conn) *Client {
+	size := config.GetConfig().Server.SendQueueSize
+	if size <= 0 {
+		size = DEFAULT_SEND_QUEUE_SIZE
+	}
+	return &Client{
+		Conn:        conn,
+		Name:        name,
+		DeviceId:    deviceId,
+		DeviceType:  deviceType,
+		GroupFrame:  groupFrame,
+		ConnectedAt: time.Now(),
+		send:        make(chan *outbound, size),
+	}
+}
+
+// enqueue 方法把不需要记录游标的消息放入发送队列，队列已满时按配置的慢消费者策略处理
+func (c *Client) enqueue(data []byte) {
+	c.push(&outbound{data: data}, config.GetConfig().Server.SlowConsumerPolicy)
+}
+
+// deliver 方法把已持久化的消息放入发送队列，写入连接后推进该会话的投递游标
+func (c *Client) deliver(data []byte, messageType int32, conversation string, id int64) {
+	c.push(&outbound{data: data, messageType: messageType, conversation: conversation, id: id}, config.GetConfig().Server.SlowConsumerPolicy)
+}
+
+// push 方法以非阻塞的方式放入发送队列，一个慢连接不会阻塞路由worker
+// 处于离线补发状态时，带游标的消息不再入队，投递游标停在已写入的位置，队列清空后由Write协程从数据库补发
+func (c *Client) push(item *outbound, policy string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return
+	}
+	if c.spilled && item.id > 0 {
+		sendSpilled.Add(1)
+		return
+	}
+
+	select {
+	case c.send <- item:
+		return
+	default:
+	}
+
+	switch policy {
+	case constant.SLOW_CONSUMER_DROP_OLDEST:
+		select { // 丢弃最旧的一条，为新消息腾出位置
+		case <-c.send:
+		default:
+		}
+		sendDropped.Add(1)
+		select {
+		case c.send <- item:
+		default:
+			sendDropped.Add(1)
+		}
+	case constant.SLOW_CONSUMER_OFFLINE:
+		if item.id > 0 {
+			c.spilled = true
+			sendSpilled.Add(1)
+		} else {
+			sendDropped.Add(1) // 不持久化的消息无法补发，直接丢弃
+		}
+	default:
+		if !c.slow {
+			c.slow = true
+			slowDisconnects.Add(1)
+			go c.closeConn(CLOSE_SLOW_CONSUMER, "slow consumer, reconnect") // 未写入的消息在重连后作为离线消息补发
+		}
+	}
+}
+
+// resume 方法在发送队列清空后退出离线补发状态，返回之前是否处于离线补发状态，只在Write协程中调用
+// 退出后新路由的消息重新入队，之前未入队的消息已持久化，随后从数据库补发时一定能查到
+func (c *Client) resume() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	spilled := c.spilled
+	c.spilled = false
+	return spilled
+}
+
+// queueStats 方法统计所有连接的发送队列深度，以及路由worker中等待处理的消息数
+func (s *Server) queueStats() map[string]int {
+	stats := map[string]int{"clients": 0, "queued": 0, "max": 0, "routing": s.dispatcher.Pending()}
+	s.clients.Range(func(session hub.Session) {
+		depth := len(session.(*Client).send)
+		stats["clients"]++
+		stats["queued"] += depth
+		if depth > stats["max"] {
+			stats["max"] = depth
+		}
+	})
+	return stats
+}
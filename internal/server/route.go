@@ -0,0 +1,68 @@
+package server
+
+import (
+	"chat-room/internal/service"    // 引入服务层，用于查询群成员、好友和用户所在的节点
+	"chat-room/pkg/common/constant" // 引入常量包，定义了消息类型
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"chat-room/pkg/protocol"        // 引入协议包，用于解析消息
+
+	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于反序列化消息
+)
+
+// routeKafkaMsg 函数找出路由主题中的消息涉及的用户所在的节点，返回消息的key和这些节点的id
+// 路由主题由所有节点组成的消费者组共同消费，每条消息只会被一个节点路由一次
+// 查询路由表失败时返回错误，消息稍后重新路由；无法解析的消息直接丢弃
+func routeKafkaMsg(data []byte) (string, []string, error) {
+	msg := &protocol.Message{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		log.Logger.Error("route unmarshal message error", log.String("route unmarshal message error", err.Error()))
+		return "", nil, nil
+	}
+
+	var nodes []string
+	var err error
+	if msg.To == "" || msg.Type == constant.PRESENCE {
+		// 广播消息和在线状态需要更新所有节点的状态，转发给所有有用户连接的节点
+		nodes, err = service.NodeService.GetAllNodes()
+	} else {
+		nodes, err = service.NodeService.GetNodes(targetUsers(msg))
+	}
+	if err != nil {
+		log.Logger.Error("route get nodes error", log.String("type", msg.Type), log.String("route get nodes error", err.Error()))
+		return "", nil, err
+	}
+	return routingKey(msg), nodes, nil
+}
+
+// targetUsers 函数返回需要接收消息的用户，发送者本身也包含在内，用于同步到发送者的其他设备
+// 群组系统消息还包含涉及的成员，该成员可能已经离开群组
+func targetUsers(msg *protocol.Message) []string {
+	users := []string{msg.From}
+	if msg.Target != "" {
+		users = append(users, msg.Target)
+	}
+	if msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+		for _, member := range service.GroupService.GetGroupMemberUuids(msg.To) {
+			if member != msg.From {
+				users = append(users, member)
+			}
+		}
+		return users
+	}
+	return append(users, msg.To)
+}
+
+// routingKey 函数返回消息在Kafka中的key，同一会话的消息使用相同的key，保证会话内的消息有序
+// 单聊会话的key与双方的先后顺序无关，群聊为群组uuid，在线状态、远程下线和广播消息为发送者uuid
+func routingKey(msg *protocol.Message) string {
+	if msg.To == "" || msg.Type == constant.PRESENCE || msg.Type == constant.LOGOUT {
+		return msg.From
+	}
+	if msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+		return msg.To
+	}
+	if msg.From < msg.To {
+		return msg.From + ":" + msg.To
+	}
+	return msg.To + ":" + msg.From
+}
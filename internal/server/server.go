@@ -2,161 +2,311 @@ package server
 
 import (
 	"chat-room/config"              // 引入配置包，用于读取配置信息
+	"chat-room/internal/hub"        // 引入hub包，提供分片的连接注册表和路由worker
+	"chat-room/internal/model"      // 引入数据模型包，定义了数据库表结构
 	"chat-room/internal/service"    // 引入服务层，用于业务逻辑处理
 	"chat-room/pkg/common/constant" // 引入常量包，用于定义全局常量
 	"chat-room/pkg/common/util"     // 引入工具包，提供各种实用函数
+	"chat-room/pkg/errors"          // 引入自定义错误包
 	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
 	"chat-room/pkg/protocol"        // 引入协议包，用于消息协议处理
 	"encoding/base64"               // 引入base64编码解码库
 	"io/ioutil"                     // 引入I/O实用函数库，用于文件读写
+	"runtime"                       // 引入运行时包，用于获取CPU核数
 	"strings"                       // 引入字符串处理库
 	"sync"                          // 引入同步包，用于并发控制
 
 	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于序列化和反序列化消息
 	"github.com/google/uuid"         // 引入UUID库，用于生成唯一标识符
+	"github.com/gorilla/websocket"   // 引入Gorilla WebSocket库，用于预先编码群消息的WebSocket帧
 )
 
 // MyServer 是全局的Server实例，用于管理WebSocket客户端
 var MyServer = NewServer()
 
+// DEFAULT_HUB_QUEUE_SIZE 每个路由worker的队列长度
+const DEFAULT_HUB_QUEUE_SIZE = 1024
+
 // Server 结构体用于管理连接的客户端和消息的处理
+// 连接登记在按用户分片的注册表中，可以并发查询；消息按会话分配给固定的worker路由，同一会话的消息保持有序
 type Server struct {
-	Clients   map[string]*Client // 存储连接的客户端，以客户端名称为键
-	mutex     *sync.Mutex        // 互斥锁，用于保护共享资源
-	Broadcast chan []byte        // 广播通道，用于发送消息给所有客户端
-	Register  chan *Client       // 注册通道，用于注册新客户端
-	Ungister  chan *Client       // 注销通道，用于注销客户端
+	clients    *hub.Registry        // 存储连接的客户端，以用户uuid和设备id为键，同一用户可以有多个设备同时在线
+	dispatcher *hub.Dispatcher      // 路由worker，按会话分片处理消息总线中需要投递给本节点客户端的消息
+	mutex      *sync.Mutex          // 互斥锁，用于保护以下状态
+	presences  map[string]*presence // 用户的在线状态，以用户uuid为键，由mutex保护
+	draining   bool                 // 是否正在关闭服务，关闭期间不再接受新的连接，由mutex保护
+	saving     int                  // 正在保存的消息数，关闭服务时等待其归零，由mutex保护
+	writers    int                  // 运行中的Write协程数，关闭服务时等待其保存游标后退出，由mutex保护
+}
+
+// routed 是等待worker路由的一条消息
+type routed struct {
+	data []byte            // 消息总线中收到的原始消息
+	msg  *protocol.Message // 反序列化后的消息
 }
 
-// NewServer 初始化并返回一个Server实例
+// NewServer 初始化并返回一个Server实例，并启动路由worker，worker数取自配置，没有配置时使用CPU核数
 func NewServer() *Server {
-	return &Server{
+	shards := config.GetConfig().Server.HubShards
+	if shards <= 0 {
+		shards = runtime.NumCPU()
+	}
+	s := &Server{
 		mutex:     &sync.Mutex{},
-		Clients:   make(map[string]*Client),
-		Broadcast: make(chan []byte),
-		Register:  make(chan *Client),
-		Ungister:  make(chan *Client),
+		clients:   hub.NewRegistry(shards),
+		presences: make(map[string]*presence),
 	}
+	s.dispatcher = hub.NewDispatcher(shards, DEFAULT_HUB_QUEUE_SIZE, func(item interface{}) {
+		r := item.(*routed)
+		s.route(r.data, r.msg)
+	})
+	return s
 }
 
-// ConsumerKafkaMsg 函数用于消费Kafka中的消息，并将消息发送到Broadcast通道
-func ConsumerKafkaMsg(data []byte) {
-	MyServer.Broadcast <- data
+// ConsumerMsg 函数用于接收消息总线中需要本节点处理的消息，按会话交给对应的路由worker，只投递给本节点连接的客户端
+func ConsumerMsg(data []byte) {
+	msg := &protocol.Message{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		log.Logger.Error("consumer unmarshal message error", log.String("consumer unmarshal message error", err.Error()))
+		return
+	}
+	MyServer.dispatcher.Dispatch(routingKey(msg), &routed{data: data, msg: msg})
 }
 
-// Start 函数启动服务器，处理客户端注册、注销和消息广播
-func (s *Server) Start() {
-	log.Logger.Info("start server", log.Any("start server", "start server..."))
-	for {
-		select {
-		case conn := <-s.Register: // 处理新客户端注册
-			log.Logger.Info("login", log.Any("login", "new user login in"+conn.Name))
-			s.Clients[conn.Name] = conn
-			msg := &protocol.Message{
-				From:    "System",
-				To:      conn.Name,
-				Content: "welcome!",
-			}
-			protoMsg, _ := proto.Marshal(msg)
-			conn.Send <- protoMsg
-
-		case conn := <-s.Ungister: // 处理客户端注销
-			log.Logger.Info("loginout", log.Any("loginout", conn.Name))
-			if _, ok := s.Clients[conn.Name]; ok {
-				close(conn.Send)
-				delete(s.Clients, conn.Name)
-			}
+// Register 方法注册新客户端，在建立连接的goroutine中调用，查询数据库的工作不会阻塞消息路由
+// 离线消息由Write协程在开始写入队列之前补发
+func (s *Server) Register(conn *Client) {
+	log.Logger.Info("login", log.String("login", conn.Name), log.String("device", conn.DeviceId))
+	old, firstDevice := s.clients.Add(conn)
+	if old != nil {
+		old.(*Client).Conn.Close() // 同一设备重新连接，关闭旧连接，旧连接注销时不会再匹配到该设备
+	}
+	if s.Draining() { // 关闭服务期间完成升级的连接直接通知客户端重连，随后走正常的注销流程
+		conn.goingAway()
+	}
+	if trackNodes() { // 记录用户所在的节点，路由主题只把相关消息转发到本节点，并按集群中的设备数判断是否第一个设备上线
+		if err := service.NodeService.Register(conn.Name, conn.DeviceId, conn.DeviceType, conn.Conn.RemoteAddr().String(), config.GetConfig().MsgChannelType.NodeId); err != nil {
+			log.Logger.Error("register user node error", log.String("register user node error", err.Error()))
+		} else if count, err := service.NodeService.CountDevices(conn.Name); err == nil {
+			firstDevice = count == 1
+		}
+	}
+	sendFriendPresences(conn, s)
+	if firstDevice { // 第一个设备上线时才发布在线状态
+		publishPresence(conn.Name, constant.PRESENCE_ONLINE)
+	}
+	msg := &protocol.Message{
+		From:    "System",
+		To:      conn.Name,
+		Content: "welcome!",
+	}
+	protoMsg, _ := proto.Marshal(msg)
+	conn.enqueue(protoMsg)
+}
 
-		case message := <-s.Broadcast: // 处理广播消息
-			msg := &protocol.Message{}
-			proto.Unmarshal(message, msg)
-
-			if msg.To != "" {
-				// 处理点对点消息或群组消息
-				if msg.ContentType >= constant.TEXT && msg.ContentType <= constant.VIDEO {
-					// 保存消息到数据库
-					_, exists := s.Clients[msg.From]
-					if exists {
-						saveMessage(msg)
-					}
-
-					// 单聊消息
-					if msg.MessageType == constant.MESSAGE_TYPE_USER {
-						client, ok := s.Clients[msg.To]
-						if ok {
-							msgByte, err := proto.Marshal(msg)
-							if err == nil {
-								client.Send <- msgByte
-							}
-						}
-					} else if msg.MessageType == constant.MESSAGE_TYPE_GROUP {
-						// 群聊消息
-						sendGroupMessage(msg, s)
-					}
-				} else {
-					// 处理语音或视频聊天，直接转发消息
-					client, ok := s.Clients[msg.To]
-					if ok {
-						client.Send <- message
-					}
-				}
+// Unregister 方法注销客户端，每个连接只会在Read退出时注销一次
+func (s *Server) Unregister(conn *Client) {
+	log.Logger.Info("loginout", log.String("loginout", conn.Name), log.String("device", conn.DeviceId))
+	// 发送队列只在这里关闭，Write协程写完队列后保存投递游标
+	conn.close()
+	removed, lastDevice := s.clients.Remove(conn)
+	// 同一设备已重新连接到本节点时，路由记录属于新连接，不能删除
+	if removed && trackNodes() {
+		service.NodeService.Unregister(conn.Name, conn.DeviceId, config.GetConfig().MsgChannelType.NodeId)
+		if count, err := service.NodeService.CountDevices(conn.Name); err == nil {
+			lastDevice = count == 0 // 其他节点上还有设备在线时不发布离线状态
+		}
+	}
+	// 最后一个设备下线时才发布离线状态
+	if lastDevice {
+		publishPresence(conn.Name, constant.PRESENCE_OFFLINE)
+	}
+}
+
+// route 方法在路由worker中投递一条消息，只把消息放入客户端的发送队列，不会阻塞
+func (s *Server) route(message []byte, msg *protocol.Message) {
+	// 丢弃被接收者屏蔽的用户发来的单聊消息、已读回执、正在输入和音视频信令
+	if msg.MessageType == constant.MESSAGE_TYPE_USER && msg.To != "" && msg.Type != constant.FRIEND &&
+		service.UserService.IsBlocked(msg.To, msg.From) {
+		return
+	}
+	if msg.Type == constant.READ || msg.Type == constant.TYPING { // 已读回执和正在输入不需要持久化，原样转发给会话的另一方或群组其他成员
+		routeFrame(message, msg, s)
+		return
+	}
+	if msg.Type == constant.PRESENCE { // 在线状态变化，转发给在线的好友
+		handlePresence(message, msg, s)
+		return
+	}
+	if msg.Type == constant.LOGOUT { // 远程下线，关闭本节点上该设备的连接
+		handleLogout(msg, s)
+		return
+	}
+	if msg.Type == constant.SYSTEM { // 群组系统消息，发送给群组成员和涉及的成员
+		sendGroupEvent(message, msg, s)
+		return
+	}
+	if msg.Type == constant.FRIEND { // 好友通知，发送给双方
+		sendFriendEvent(message, msg, s)
+		return
+	}
+	if msg.Type == constant.RECALL || msg.Type == constant.EDIT { // 消息撤回和编辑通知，发送给会话的双方或群组成员
+		sendMessageEvent(message, msg, s)
+		return
+	}
 
-			} else {
-				// 广播消息，发送给所有客户端
-				for id, conn := range s.Clients {
-					log.Logger.Info("allUser", log.Any("allUser", id))
-
-					select {
-					case conn.Send <- message:
-					default:
-						close(conn.Send)
-						delete(s.Clients, conn.Name)
-					}
+	if msg.To != "" {
+		// 处理点对点消息或群组消息
+		if msg.ContentType >= constant.TEXT && msg.ContentType <= constant.VIDEO {
+			// 消息已在发送者所在连接的Client.Read中持久化，这里只负责转发
+			// 单聊消息
+			if msg.MessageType == constant.MESSAGE_TYPE_USER {
+				for _, client := range s.devices(msg.To) {
+					client.deliver(message, msg.MessageType, msg.From, msg.Id)
 				}
+				s.syncToOtherDevices(message, msg) // 同步到发送者的其他设备
+			} else if msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+				// 群聊消息
+				sendGroupMessage(message, msg, s)
+			}
+		} else {
+			// 处理语音或视频聊天，直接转发消息
+			for _, client := range s.devices(msg.To) {
+				client.enqueue(message)
 			}
 		}
+
+	} else {
+		// 广播消息，发送给所有客户端
+		s.clients.Range(func(session hub.Session) {
+			session.(*Client).enqueue(message)
+		})
 	}
 }
 
-// sendGroupMessage 函数发送群组消息，遍历群组所有成员并逐个发送
-func sendGroupMessage(msg *protocol.Message, s *Server) {
-	// 获取群组成员列表
-	users := service.GroupService.GetUserIdByGroupUuid(msg.To)
-	for _, user := range users {
-		if user.Uuid == msg.From {
-			continue
+// devices 方法返回用户在本节点所有在线设备的客户端
+func (s *Server) devices(userUuid string) []*Client {
+	sessions := s.clients.Devices(userUuid)
+	clients := make([]*Client, 0, len(sessions))
+	for _, session := range sessions {
+		clients = append(clients, session.(*Client))
+	}
+	return clients
+}
+
+// syncToOtherDevices 方法把发送者自己发出的消息同步到其在本节点的其他设备，发送消息的设备本身已收到ACK
+func (s *Server) syncToOtherDevices(message []byte, msg *protocol.Message) {
+	for _, client := range s.devices(msg.From) {
+		if client.DeviceId != msg.FromDeviceId {
+			client.enqueue(message)
 		}
+	}
+}
+
+// groupFrame 是一种帧格式的群消息，发给群成员和同步给发送者其他设备的outbound共享同一份序列化结果和WebSocket编码
+type groupFrame struct {
+	member *outbound // 带游标，发给群成员
+	echo   *outbound // 不带游标，同步给发送者的其他设备，发送者自己的消息不会作为离线消息补发
+}
 
-		client, ok := s.Clients[user.Uuid]
-		if !ok {
-			continue
+// newGroupFrame 函数为一种帧格式的群消息创建共享的outbound，预先编码失败时退回逐个连接编码
+func newGroupFrame(data []byte, msg *protocol.Message) *groupFrame {
+	prepared, err := websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+	if err != nil {
+		log.Logger.Error("prepare group message error", log.String("prepare group message error", err.Error()))
+		prepared = nil
+	}
+	return &groupFrame{
+		member: &outbound{data: data, prepared: prepared, messageType: msg.MessageType, conversation: msg.To, id: msg.Id},
+		echo:   &outbound{data: data, prepared: prepared},
+	}
+}
+
+// forwardGroupMessage 函数把群消息转换为forward格式：From为群组uuid，To为发送者uuid，与离线消息的格式一致
+// 发送者的用户名和头像已在Client.Read中以服务端数据为准填写
+func forwardGroupMessage(msg *protocol.Message) ([]byte, error) {
+	return proto.Marshal(&protocol.Message{
+		Avatar:       msg.Avatar,
+		FromUsername: msg.FromUsername,
+		From:         msg.To,
+		To:           msg.From,
+		Content:      msg.Content,
+		ContentType:  msg.ContentType,
+		Type:         msg.Type,
+		MessageType:  msg.MessageType,
+		Url:          msg.Url,
+		Id:           msg.Id,
+		Timestamp:    msg.Timestamp,
+		ClientMsgId:  msg.ClientMsgId,
+		FromDeviceId: msg.FromDeviceId,
+	})
+}
+
+// sendGroupMessage 函数发送群组消息，发送给群组所有成员的所有设备，发送者的其他设备同样会收到
+// 每种帧格式最多序列化一次，所有成员共享同一个outbound，扇出时按注册表分片批量入队，不为每个成员复制消息
+// compact格式直接使用消息总线中收到的message，不需要重新序列化
+func sendGroupMessage(message []byte, msg *protocol.Message, s *Server) {
+	var forward, compact *groupFrame
+	policy := config.GetConfig().Server.SlowConsumerPolicy
+
+	members := service.GroupService.GetGroupMemberUuids(msg.To)
+	s.clients.ForEach(members, func(session hub.Session) {
+		client := session.(*Client)
+		if client.Name == msg.From && client.DeviceId == msg.FromDeviceId { // 发送消息的设备本身已收到ACK
+			return
 		}
 
-		// 获取发送者详情
-		fromUserDetails := service.UserService.GetUserDetails(msg.From)
-		// 修改消息的From字段，使其表示群组
-		msgSend := protocol.Message{
-			Avatar:       fromUserDetails.Avatar,
-			FromUsername: msg.FromUsername,
-			From:         msg.To,
-			To:           msg.From,
-			Content:      msg.Content,
-			ContentType:  msg.ContentType,
-			Type:         msg.Type,
-			MessageType:  msg.MessageType,
-			Url:          msg.Url,
+		var frame *groupFrame
+		if client.GroupFrame == constant.GROUP_FRAME_COMPACT {
+			if compact == nil {
+				compact = newGroupFrame(message, msg)
+			}
+			frame = compact
+		} else {
+			if forward == nil {
+				data, err := forwardGroupMessage(msg)
+				if err != nil {
+					log.Logger.Error("marshal group message error", log.String("marshal group message error", err.Error()))
+					return
+				}
+				forward = newGroupFrame(data, msg)
+			}
+			frame = forward
 		}
 
-		// 将消息序列化并发送给群成员
-		msgByte, err := proto.Marshal(&msgSend)
-		if err == nil {
-			client.Send <- msgByte
+		if client.Name == msg.From {
+			client.push(frame.echo, policy)
+			return
 		}
+		client.push(frame.member, policy)
+	})
+}
+
+// routeFrame 函数将不需要持久化的控制帧原样转发：单聊转发给接收者，群聊转发给除发送者外的所有群成员
+// 已读回执还会同步到发送者的其他设备，使各设备的未读数保持一致
+func routeFrame(message []byte, msg *protocol.Message, s *Server) {
+	if msg.Type == constant.READ {
+		s.syncToOtherDevices(message, msg)
+	}
+
+	if msg.MessageType == constant.MESSAGE_TYPE_GROUP {
+		item := &outbound{data: message} // 所有成员共享同一个outbound
+		policy := config.GetConfig().Server.SlowConsumerPolicy
+		s.clients.ForEach(service.GroupService.GetGroupMemberUuids(msg.To), func(session hub.Session) {
+			if client := session.(*Client); client.Name != msg.From {
+				client.push(item, policy)
+			}
+		})
+		return
+	}
+
+	for _, client := range s.devices(msg.To) {
+		client.enqueue(message)
 	}
 }
 
-// saveMessage 函数保存消息，如果是文件消息则保存文件并更新消息内容
-func saveMessage(message *protocol.Message) {
+// saveMessage 函数保存消息，如果是文件消息则保存文件并更新消息内容，返回保存后的消息记录
+func saveMessage(message *protocol.Message) (*model.Message, error) {
 	// 处理base64编码的文件内容
 	if message.ContentType == 2 {
 		url := uuid.New().String() + ".png"
@@ -169,12 +319,12 @@ func saveMessage(message *protocol.Message) {
 		dataBuffer, dataErr := base64.StdEncoding.DecodeString(content)
 		if dataErr != nil {
 			log.Logger.Error("transfer base64 to file error", log.String("transfer base64 to file error", dataErr.Error()))
-			return
+			return nil, errors.New("文件解析失败")
 		}
 		err := ioutil.WriteFile(config.GetConfig().StaticPath.FilePath+url, dataBuffer, 0666)
 		if err != nil {
 			log.Logger.Error("write file error", log.String("write file error", err.Error()))
-			return
+			return nil, errors.New("文件保存失败")
 		}
 		message.Url = url
 		message.Content = ""
@@ -189,7 +339,7 @@ func saveMessage(message *protocol.Message) {
 		err := ioutil.WriteFile(config.GetConfig().StaticPath.FilePath+url, message.File, 0666)
 		if err != nil {
 			log.Logger.Error("write file error", log.String("write file error", err.Error()))
-			return
+			return nil, errors.New("文件保存失败")
 		}
 		message.Url = url
 		message.File = nil
@@ -197,5 +347,5 @@ func saveMessage(message *protocol.Message) {
 	}
 
 	// 将消息保存到数据库
-	service.MessageService.SaveMessage(*message)
+	return service.MessageService.SaveMessage(*message)
 }
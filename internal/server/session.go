@@ -0,0 +1,93 @@
+package server
+
+import (
+	"chat-room/internal/service"    // 引入服务层，用于查询集群中的在线设备和吊销设备的token
+	"chat-room/pkg/common/constant" // 引入常量包，定义了远程下线的消息类型
+	"chat-room/pkg/common/response" // 引入通用响应包，定义了会话的响应
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"chat-room/pkg/protocol"        // 引入协议包，用于构造远程下线消息
+	"sort"                          // 引入排序包，按连接时间排序会话
+	"time"                          // 引入时间包，用于设置关闭帧的写超时
+
+	"github.com/gogo/protobuf/proto" // 引入protobuf库，用于序列化消息
+	"github.com/gorilla/websocket"   // 引入Gorilla WebSocket库，用于发送关闭帧
+)
+
+// CLOSE_REMOTE_LOGOUT 被远程下线时关闭帧使用的状态码，4000-4999为应用自定义状态码
+const CLOSE_REMOTE_LOGOUT = 4001
+
+// GetSessions 方法获取用户所有在线设备的会话，按连接时间排序
+// 多节点部署时从路由表中查询整个集群的设备，单机部署时查询本节点的注册表
+func (s *Server) GetSessions(userUuid string) ([]response.SessionResponse, error) {
+	var sessions []response.SessionResponse
+	if trackNodes() {
+		nodes, err := service.NodeService.GetSessions(userUuid)
+		if err != nil {
+			return nil, err
+		}
+		sessions = make([]response.SessionResponse, 0, len(nodes))
+		for _, node := range nodes {
+			sessions = append(sessions, response.SessionResponse{
+				DeviceId:    node.DeviceId,
+				DeviceType:  node.DeviceType,
+				RemoteAddr:  node.RemoteAddr,
+				ConnectedAt: node.CreatedAt.UnixNano() / int64(time.Millisecond),
+			})
+		}
+	} else {
+		clients := s.devices(userUuid)
+		sessions = make([]response.SessionResponse, 0, len(clients))
+		for _, client := range clients {
+			sessions = append(sessions, response.SessionResponse{
+				DeviceId:    client.DeviceId,
+				DeviceType:  client.DeviceType,
+				RemoteAddr:  client.Conn.RemoteAddr().String(),
+				ConnectedAt: client.ConnectedAt.UnixNano() / int64(time.Millisecond),
+			})
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ConnectedAt < sessions[j].ConnectedAt
+	})
+	return sessions, nil
+}
+
+// KickSession 方法将用户指定设备远程下线：先使该设备的token失效，防止设备重连，再通过消息总线通知连接所在的节点关闭连接
+// 设备从未登录过时返回错误，设备不在线时只使token失效
+func (s *Server) KickSession(userUuid, deviceId string) error {
+	if err := service.UserService.RevokeDeviceTokens(userUuid, deviceId); err != nil {
+		return err
+	}
+
+	msg := &protocol.Message{
+		Type:      constant.LOGOUT,
+		From:      userUuid,
+		To:        userUuid,
+		Content:   deviceId,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	msgByte, err := proto.Marshal(msg)
+	if err != nil {
+		log.Logger.Error("marshal logout error", log.String("marshal logout error", err.Error()))
+		return err
+	}
+	publish(msg, msgByte, nil)
+	return nil
+}
+
+// handleLogout 函数处理远程下线消息：设备连接在本节点时发送关闭帧后关闭连接，由该连接的Read协程退出时注销
+func handleLogout(msg *protocol.Message, s *Server) {
+	session, ok := s.clients.Get(msg.From, msg.Content)
+	if !ok {
+		return
+	}
+	session.(*Client).closeConn(CLOSE_REMOTE_LOGOUT, "logged out remotely")
+}
+
+// closeConn 方法向客户端发送带状态码和原因的关闭帧后关闭连接，由Read协程退出时注销
+func (c *Client) closeConn(code int, reason string) {
+	// WriteControl和Close可以与其他写操作并发调用
+	closeMessage := websocket.FormatCloseMessage(code, reason)
+	c.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	c.Conn.Close()
+}
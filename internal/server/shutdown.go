@@ -0,0 +1,99 @@
+package server
+
+import (
+	"chat-room/config"       // 引入配置包，用于读取关闭服务的期限
+	"chat-room/internal/hub" // 引入hub包，用于遍历连接注册表
+	"context"                // 引入上下文包，用于控制关闭服务的期限
+	"time"                   // 引入时间包，用于轮询客户端是否全部注销
+
+	"github.com/gorilla/websocket" // 引入Gorilla WebSocket库，用于发送关闭帧
+)
+
+const (
+	SHUTDOWN_POLL_INTERVAL   = 50 * time.Millisecond // 关闭服务时检查客户端是否全部注销的间隔
+	DEFAULT_SHUTDOWN_TIMEOUT = 15 * time.Second      // 没有配置时关闭服务的最长等待时间
+)
+
+// ShutdownTimeout 函数返回关闭服务时等待连接断开和消息保存完成的最长时间
+func ShutdownTimeout() time.Duration {
+	if timeout := config.GetConfig().Server.ShutdownTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return DEFAULT_SHUTDOWN_TIMEOUT
+}
+
+// Draining 方法返回服务是否正在关闭，关闭期间不再接受新的WebSocket连接
+func (s *Server) Draining() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.draining
+}
+
+// beginSave 方法登记一次在途的消息保存
+func (s *Server) beginSave() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.saving++
+}
+
+// beginWrite 方法登记一个运行中的Write协程
+func (s *Server) beginWrite() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.writers++
+}
+
+// endWrite 方法结束一个Write协程
+func (s *Server) endWrite() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.writers--
+}
+
+// endSave 方法结束一次在途的消息保存
+func (s *Server) endSave() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.saving--
+}
+
+// Shutdown 方法关闭所有WebSocket连接：停止接受新连接，向每个客户端发送"going away"关闭帧，
+// 然后等待所有客户端注销、Write协程保存投递游标后退出，以及在途的消息保存完成，超过ctx的期限时返回ctx的错误
+func (s *Server) Shutdown(ctx context.Context) error {
+	// 先设置关闭标记再遍历注册表，之后注册的连接会在Register中自行收到关闭帧
+	s.mutex.Lock()
+	s.draining = true
+	s.mutex.Unlock()
+
+	s.clients.Range(func(session hub.Session) {
+		session.(*Client).goingAway()
+	})
+
+	ticker := time.NewTicker(SHUTDOWN_POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		clients := s.clients.Len()
+		s.mutex.Lock()
+		done := clients == 0 && s.saving == 0 && s.writers == 0
+		s.mutex.Unlock()
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// goingAway 方法通知客户端服务即将关闭需要重连，并关闭连接
+func (c *Client) goingAway() {
+	c.closeConn(websocket.CloseGoingAway, "server going away, reconnect")
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"   // 引入数据库连接池
+	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/pkg/common/constant" // 引入常量包，定义了好友请求的状态
+	"chat-room/pkg/errors"          // 引入自定义错误处理包
+)
+
+// BlockUser 函数用于将用户加入黑名单，被屏蔽用户发来的待处理好友请求同时被拒绝
+func (u *userService) BlockUser(userUuid, blockedUuid string) error {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.UserBlock{}) // 自动迁移黑名单表结构，确保表存在
+
+	var queryUser model.User
+	db.First(&queryUser, "uuid = ?", userUuid)
+	var blocked model.User
+	db.First(&blocked, "uuid = ?", blockedUuid)
+	if NULL_ID == queryUser.Id || NULL_ID == blocked.Id {
+		return errors.New("用户不存在")
+	}
+	if queryUser.Id == blocked.Id {
+		return errors.New("不能屏蔽自己")
+	}
+
+	var userBlock model.UserBlock
+	db.First(&userBlock, "user_id = ? AND blocked_id = ?", queryUser.Id, blocked.Id)
+	if userBlock.ID != NULL_ID {
+		return errors.New("已经屏蔽该用户")
+	}
+	if err := db.Create(&model.UserBlock{UserId: queryUser.Id, BlockedId: blocked.Id}).Error; err != nil {
+		return errors.New("屏蔽用户失败")
+	}
+	db.Model(&model.FriendRequest{}).
+		Where("from_id = ? AND to_id = ? AND status = ?", blocked.Id, queryUser.Id, constant.FRIEND_REQUEST_PENDING).
+		Update("status", constant.FRIEND_REQUEST_REJECTED)
+	invalidateUserBlocks(userUuid)
+	return nil
+}
+
+// UnblockUser 函数用于将用户移出黑名单
+func (u *userService) UnblockUser(userUuid, blockedUuid string) error {
+	db := pool.GetDB()
+	var queryUser model.User
+	db.First(&queryUser, "uuid = ?", userUuid)
+	var blocked model.User
+	db.First(&blocked, "uuid = ?", blockedUuid)
+	if NULL_ID == queryUser.Id || NULL_ID == blocked.Id {
+		return errors.New("用户不存在")
+	}
+
+	result := db.Where("user_id = ? AND blocked_id = ?", queryUser.Id, blocked.Id).Delete(&model.UserBlock{})
+	if result.Error != nil {
+		return errors.New("解除屏蔽失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("没有屏蔽该用户")
+	}
+	invalidateUserBlocks(userUuid)
+	return nil
+}
+
+// GetBlockedUsers 函数获取用户黑名单中用户的uuid、用户名、昵称和头像
+func (u *userService) GetBlockedUsers(userUuid string) []model.User {
+	return getUserProfiles(u.getBlockedUuids(userUuid))
+}
+
+// IsBlocked 函数判断userUuid是否屏蔽了senderUuid，用于投递单聊消息、发送好友请求和查找用户，优先从缓存读取
+func (u *userService) IsBlocked(userUuid, senderUuid string) bool {
+	for _, blocked := range u.getBlockedUuids(userUuid) {
+		if blocked == senderUuid {
+			return true
+		}
+	}
+	return false
+}
+
+// getBlockedUuids 函数获取用户屏蔽的所有用户uuid，结果写入缓存，没有屏蔽任何人时缓存空列表
+func (u *userService) getBlockedUuids(userUuid string) []string {
+	uuids := []string{}
+	if getCached(USER_BLOCKS_CACHE_KEY+userUuid, &uuids) {
+		return uuids
+	}
+
+	pool.GetDB().Raw("SELECT b.uuid FROM users AS u JOIN user_blocks AS ub ON ub.user_id = u.id AND ub.deleted_at = 0 JOIN users AS b ON b.id = ub.blocked_id WHERE u.uuid = ? ORDER BY ub.id",
+		userUuid).Scan(&uuids)
+	setCached(USER_BLOCKS_CACHE_KEY+userUuid, uuids)
+	return uuids
+}
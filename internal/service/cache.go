@@ -0,0 +1,112 @@
+package service
+
+import (
+	"chat-room/config"              // 引入配置包，用于读取缓存配置
+	"chat-room/internal/cache"      // 引入缓存包，提供进程内和Redis缓存
+	"chat-room/internal/dao/pool"   // 引入数据库连接池和Redis客户端
+	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/pkg/common/constant" // 引入常量包，定义了缓存类型
+	"chat-room/pkg/global/log"      // 引入全局日志记录器，用于日志记录
+	"encoding/json"                 // 引入JSON包，用于序列化缓存的值
+	"time"                          // 引入时间包，用于设置缓存的过期时间
+)
+
+const (
+	REDIS_CACHE_PREFIX      = "go-chat:cache:" // Redis缓存键的前缀
+	GROUP_MEMBERS_CACHE_KEY = "group:members:" // 群成员uuid列表的缓存键前缀，后接群组uuid
+	GROUP_SPEAK_CACHE_KEY   = "group:speak:"   // 群组发言状态的缓存键前缀，后接群组uuid，值为群主、全员禁言和成员的角色与禁言状态
+	USER_PROFILE_CACHE_KEY  = "user:profile:"  // 用户资料的缓存键前缀，后接用户uuid
+	USER_BLOCKS_CACHE_KEY   = "user:blocks:"   // 用户黑名单的缓存键前缀，后接用户uuid，值为被屏蔽用户的uuid列表
+	DEVICE_TOKEN_CACHE_KEY  = "device:token:"  // 设备token版本的缓存键前缀，后接用户uuid和设备id
+)
+
+// profileCache 缓存群成员列表、用户资料、黑名单和设备token版本，默认使用进程内缓存，启动时由InitCache按配置替换
+// 群成员列表只缓存成员uuid，用户资料单独缓存，修改资料时只需要使一个键失效
+var profileCache = cache.NewLRUCache(cache.DEFAULT_LRU_SIZE, cache.DEFAULT_TTL)
+
+// InitCache 函数根据配置初始化群成员和用户资料缓存
+func InitCache() {
+	cacheConfig := config.GetConfig().Cache
+	ttl := time.Duration(cacheConfig.Ttl) * time.Second
+	if cacheConfig.Engine == constant.CACHE_REDIS {
+		profileCache = cache.NewRedisCache(pool.GetRedis(), REDIS_CACHE_PREFIX, ttl)
+		return
+	}
+	profileCache = cache.NewLRUCache(cacheConfig.Size, ttl)
+}
+
+// getCached 函数读取缓存并反序列化到value，未命中或反序列化失败时返回false
+func getCached(key string, value interface{}) bool {
+	data, ok := profileCache.Get(key)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		log.Logger.Error("unmarshal cache error", log.String("key", key), log.String("unmarshal cache error", err.Error()))
+		return false
+	}
+	return true
+}
+
+// setCached 函数序列化value后写入缓存
+func setCached(key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Logger.Error("marshal cache error", log.String("key", key), log.String("marshal cache error", err.Error()))
+		return
+	}
+	profileCache.Set(key, data)
+}
+
+// invalidateGroupMembers 函数在群成员、成员角色或禁言状态变化后使群成员列表和发言状态的缓存失效
+func invalidateGroupMembers(groupUuid string) {
+	profileCache.Delete(GROUP_MEMBERS_CACHE_KEY + groupUuid)
+	profileCache.Delete(GROUP_SPEAK_CACHE_KEY + groupUuid)
+}
+
+// invalidateUserProfile 函数在用户修改资料后使用户资料的缓存失效
+func invalidateUserProfile(userUuid string) {
+	profileCache.Delete(USER_PROFILE_CACHE_KEY + userUuid)
+}
+
+// invalidateUserBlocks 函数在用户修改黑名单后使黑名单的缓存失效
+func invalidateUserBlocks(userUuid string) {
+	profileCache.Delete(USER_BLOCKS_CACHE_KEY + userUuid)
+}
+
+// invalidateDeviceToken 函数在设备被远程下线后使设备token版本的缓存失效
+func invalidateDeviceToken(userUuid, deviceId string) {
+	profileCache.Delete(DEVICE_TOKEN_CACHE_KEY + userUuid + ":" + deviceId)
+}
+
+// getUserProfiles 函数按uuid顺序获取用户的uuid、用户名、昵称和头像，未命中缓存的用户通过一次查询批量获取并写入缓存
+// 不存在的用户不会出现在结果中
+func getUserProfiles(uuids []string) []model.User {
+	profiles := make(map[string]model.User, len(uuids))
+	var missing []string
+	for _, uuid := range uuids {
+		var profile model.User
+		if getCached(USER_PROFILE_CACHE_KEY+uuid, &profile) {
+			profiles[uuid] = profile
+		} else {
+			missing = append(missing, uuid)
+		}
+	}
+
+	if len(missing) > 0 {
+		var users []model.User
+		pool.GetDB().Select("uuid", "username", "nickname", "avatar").Where("uuid IN ?", missing).Find(&users)
+		for _, user := range users {
+			profiles[user.Uuid] = user
+			setCached(USER_PROFILE_CACHE_KEY+user.Uuid, user)
+		}
+	}
+
+	users := make([]model.User, 0, len(uuids))
+	for _, uuid := range uuids {
+		if profile, ok := profiles[uuid]; ok {
+			users = append(users, profile)
+		}
+	}
+	return users
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool" // 引入数据库连接池
+	"chat-room/internal/model"    // 引入数据模型包
+	"chat-room/pkg/errors"        // 引入自定义错误处理包
+
+	"gorm.io/gorm" // 引入GORM库，用于原子地增加token版本
+)
+
+// getDeviceVersion 函数在设备登录时获取设备当前的token版本，设备第一次登录时创建记录
+func (u *userService) getDeviceVersion(userUuid, deviceId string) (int32, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.DeviceToken{}) // 自动迁移设备token表结构，确保表存在
+
+	deviceToken := model.DeviceToken{UserUuid: userUuid, DeviceId: deviceId}
+	err := db.Where("user_uuid = ? AND device_id = ?", userUuid, deviceId).FirstOrCreate(&deviceToken).Error
+	return deviceToken.Version, err
+}
+
+// CheckDeviceToken 函数判断token中的版本是否为设备当前有效的版本，Auth中间件每次请求都会调用，优先从缓存读取
+func (u *userService) CheckDeviceToken(userUuid, deviceId string, version int32) bool {
+	key := DEVICE_TOKEN_CACHE_KEY + userUuid + ":" + deviceId
+	var current int32
+	if !getCached(key, &current) {
+		var deviceToken model.DeviceToken
+		if err := pool.GetDB().Where("user_uuid = ? AND device_id = ?", userUuid, deviceId).First(&deviceToken).Error; err != nil {
+			return false
+		}
+		current = deviceToken.Version
+		setCached(key, current)
+	}
+	return current == version
+}
+
+// RevokeDeviceTokens 函数增加设备的token版本，使该设备已签发的access token和refresh token全部失效
+func (u *userService) RevokeDeviceTokens(userUuid, deviceId string) error {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.DeviceToken{}) // 自动迁移设备token表结构，确保表存在
+
+	result := db.Model(&model.DeviceToken{}).Where("user_uuid = ? AND device_id = ?", userUuid, deviceId).
+		Update("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("设备不存在")
+	}
+	invalidateDeviceToken(userUuid, deviceId)
+	return nil
+}
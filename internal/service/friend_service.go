@@ -0,0 +1,206 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"   // 引入数据库连接池
+	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/pkg/common/constant" // 引入常量包，定义了好友请求的状态
+	"chat-room/pkg/common/request"  // 引入通用请求包
+	"chat-room/pkg/common/response" // 引入通用响应包
+	"chat-room/pkg/errors"          // 引入自定义错误处理包
+
+	"gorm.io/gorm" // 引入GORM，用于在事务中创建双向好友关系
+)
+
+// AddFriend 函数用于向指定用户名的用户发送好友请求，对方通过后双方才成为好友
+func (u *userService) AddFriend(userFriendRequest *request.FriendRequest) (*response.FriendRequestResponse, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.UserFriend{}, &model.FriendRequest{}) // 自动迁移好友和好友请求表结构
+
+	var queryUser model.User
+	db.First(&queryUser, "uuid = ?", userFriendRequest.Uuid) // 根据UUID查询用户信息
+	if NULL_ID == queryUser.Id {                             // 如果用户不存在，返回错误
+		return nil, errors.New("用户不存在")
+	}
+	var friend model.User
+	db.First(&friend, "username = ?", userFriendRequest.FriendUsername) // 根据用户名查询好友信息
+	if NULL_ID == friend.Id {
+		return nil, errors.New("用户不存在")
+	}
+	if queryUser.Id == friend.Id {
+		return nil, errors.New("不能添加自己为好友")
+	}
+	if u.IsBlocked(friend.Uuid, queryUser.Uuid) {
+		return nil, errors.New("对方拒绝接收你的好友请求")
+	}
+	if u.IsBlocked(queryUser.Uuid, friend.Uuid) {
+		return nil, errors.New("请先将对方移出黑名单")
+	}
+	if isFriend(db, queryUser.Id, friend.Id) {
+		return nil, errors.New("该用户已经是你好友")
+	}
+
+	var pending model.FriendRequest
+	db.First(&pending, "((from_id = ? AND to_id = ?) OR (from_id = ? AND to_id = ?)) AND status = ?",
+		queryUser.Id, friend.Id, friend.Id, queryUser.Id, constant.FRIEND_REQUEST_PENDING)
+	if pending.ID != NULL_ID {
+		if pending.FromId == queryUser.Id {
+			return nil, errors.New("已发送好友请求，请等待对方通过")
+		}
+		return nil, errors.New("对方已向你发送好友请求，请直接通过")
+	}
+
+	friendRequest := model.FriendRequest{
+		FromId: queryUser.Id,
+		ToId:   friend.Id,
+		Reason: userFriendRequest.Reason,
+		Status: constant.FRIEND_REQUEST_PENDING,
+	}
+	if err := db.Create(&friendRequest).Error; err != nil {
+		return nil, errors.New("发送好友请求失败")
+	}
+	return &response.FriendRequestResponse{
+		Id:        friendRequest.ID,
+		Uuid:      friend.Uuid,
+		Username:  friend.Username,
+		Nickname:  friend.Nickname,
+		Avatar:    friend.Avatar,
+		Reason:    friendRequest.Reason,
+		Status:    friendRequest.Status,
+		CreatedAt: friendRequest.CreatedAt,
+	}, nil
+}
+
+// GetFriendRequests 函数获取用户收到和发出的待处理好友请求，按发送时间倒序排列
+func (u *userService) GetFriendRequests(userUuid string) ([]response.FriendRequestResponse, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.FriendRequest{}) // 自动迁移好友请求表结构，确保表存在
+
+	var queryUser model.User
+	db.First(&queryUser, "uuid = ?", userUuid)
+	if NULL_ID == queryUser.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	var requests []response.FriendRequestResponse
+	db.Raw("SELECT fr.id, u.uuid, u.username, u.nickname, u.avatar, fr.reason, fr.status, fr.to_id = ? AS incoming, fr.created_at FROM friend_requests AS fr JOIN users AS u ON u.id = IF(fr.to_id = ?, fr.from_id, fr.to_id) WHERE (fr.from_id = ? OR fr.to_id = ?) AND fr.status = ? ORDER BY fr.id DESC",
+		queryUser.Id, queryUser.Id, queryUser.Id, queryUser.Id, constant.FRIEND_REQUEST_PENDING).Scan(&requests)
+	return requests, nil
+}
+
+// AcceptFriendRequest 函数用于接收者通过好友请求，双方各自创建一条好友关系，返回发送者的uuid
+func (u *userService) AcceptFriendRequest(userUuid string, requestId int32) (string, error) {
+	db := pool.GetDB()
+	friendRequest, _, sender, err := pendingFriendRequest(db, userUuid, requestId, true)
+	if err != nil {
+		return "", err
+	}
+	if u.IsBlocked(sender.Uuid, userUuid) || u.IsBlocked(userUuid, sender.Uuid) {
+		return "", errors.New("好友请求已失效")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := closeFriendRequest(tx, friendRequest, constant.FRIEND_REQUEST_ACCEPTED); err != nil {
+			return err
+		}
+		for _, pair := range [][2]int32{{friendRequest.FromId, friendRequest.ToId}, {friendRequest.ToId, friendRequest.FromId}} {
+			if isFriend(tx, pair[0], pair[1]) { // 旧版本添加的单向好友关系已经存在
+				continue
+			}
+			if err := tx.Create(&model.UserFriend{UserId: pair[0], FriendId: pair[1]}).Error; err != nil {
+				return errors.New("添加好友失败")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return sender.Uuid, nil
+}
+
+// RejectFriendRequest 函数用于接收者拒绝好友请求，返回发送者的uuid
+func (u *userService) RejectFriendRequest(userUuid string, requestId int32) (string, error) {
+	db := pool.GetDB()
+	friendRequest, _, sender, err := pendingFriendRequest(db, userUuid, requestId, true)
+	if err != nil {
+		return "", err
+	}
+	if err := closeFriendRequest(db, friendRequest, constant.FRIEND_REQUEST_REJECTED); err != nil {
+		return "", err
+	}
+	return sender.Uuid, nil
+}
+
+// CancelFriendRequest 函数用于发送者取消尚未处理的好友请求，返回接收者的uuid
+func (u *userService) CancelFriendRequest(userUuid string, requestId int32) (string, error) {
+	db := pool.GetDB()
+	friendRequest, receiver, _, err := pendingFriendRequest(db, userUuid, requestId, false)
+	if err != nil {
+		return "", err
+	}
+	if err := closeFriendRequest(db, friendRequest, constant.FRIEND_REQUEST_CANCELED); err != nil {
+		return "", err
+	}
+	return receiver.Uuid, nil
+}
+
+// DeleteFriend 函数用于删除好友，双方的好友关系都被逻辑删除
+func (u *userService) DeleteFriend(userUuid, friendUuid string) error {
+	db := pool.GetDB()
+	var queryUser model.User
+	db.First(&queryUser, "uuid = ?", userUuid)
+	var friend model.User
+	db.First(&friend, "uuid = ?", friendUuid)
+	if NULL_ID == queryUser.Id || NULL_ID == friend.Id {
+		return errors.New("用户不存在")
+	}
+
+	result := db.Where("(user_id = ? AND friend_id = ?) OR (user_id = ? AND friend_id = ?)",
+		queryUser.Id, friend.Id, friend.Id, queryUser.Id).Delete(&model.UserFriend{})
+	if result.Error != nil {
+		return errors.New("删除好友失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("该用户不是你的好友")
+	}
+	return nil
+}
+
+// isFriend 函数判断userId是否已把friendId添加为好友
+func isFriend(db *gorm.DB, userId, friendId int32) bool {
+	var userFriend model.UserFriend
+	db.Select("id").Find(&userFriend, "user_id = ? AND friend_id = ?", userId, friendId)
+	return userFriend.ID != NULL_ID
+}
+
+// pendingFriendRequest 函数查询待处理的好友请求及其接收者和发送者
+// incoming为true时当前用户必须是接收者，否则必须是发送者
+func pendingFriendRequest(db *gorm.DB, userUuid string, requestId int32, incoming bool) (model.FriendRequest, model.User, model.User, error) {
+	var friendRequest model.FriendRequest
+	var receiver, sender model.User
+	db.First(&friendRequest, "id = ?", requestId)
+	if friendRequest.ID == NULL_ID {
+		return friendRequest, receiver, sender, errors.New("好友请求不存在")
+	}
+	db.First(&receiver, "id = ?", friendRequest.ToId)
+	db.First(&sender, "id = ?", friendRequest.FromId)
+	if (incoming && receiver.Uuid != userUuid) || (!incoming && sender.Uuid != userUuid) {
+		return friendRequest, receiver, sender, errors.New("好友请求不存在")
+	}
+	if friendRequest.Status != constant.FRIEND_REQUEST_PENDING {
+		return friendRequest, receiver, sender, errors.New("好友请求已处理")
+	}
+	return friendRequest, receiver, sender, nil
+}
+
+// closeFriendRequest 函数把待处理的好友请求修改为status，请求已被并发处理时返回错误
+func closeFriendRequest(db *gorm.DB, friendRequest model.FriendRequest, status int16) error {
+	result := db.Model(&friendRequest).Where("status = ?", constant.FRIEND_REQUEST_PENDING).Update("status", status)
+	if result.Error != nil {
+		return errors.New("处理好友请求失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("好友请求已处理")
+	}
+	return nil
+}
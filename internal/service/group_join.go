@@ -0,0 +1,259 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool"   // 引入数据库连接池
+	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/pkg/common/constant" // 引入常量包，定义了加群方式和加群申请状态
+	"chat-room/pkg/common/response" // 引入通用响应包，用于定义响应结构
+	"chat-room/pkg/errors"          // 引入自定义错误包
+	"time"                          // 引入时间包，用于计算邀请链接的过期时间
+
+	"github.com/google/uuid" // 引入UUID库，用于生成邀请码
+	"gorm.io/gorm"           // 引入GORM，用于在事务中加入群组
+)
+
+// groupAdmin 函数查询群组和操作者，操作者必须是群主或管理员
+func groupAdmin(db *gorm.DB, groupUuid, operatorUuid string) (model.Group, model.User, error) {
+	group, operator, member, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return group, operator, err
+	}
+	if member.Role < constant.GROUP_ROLE_ADMIN {
+		return group, operator, errors.New("只有群主和管理员可以执行该操作")
+	}
+	return group, operator, nil
+}
+
+// GetGroupAdminUuids 函数获取群主和管理员的uuid，用于发送加群申请的通知
+func (g *groupService) GetGroupAdminUuids(groupUuid string) []string {
+	var uuids []string
+	pool.GetDB().Raw("SELECT u.uuid FROM `groups` AS g JOIN group_members AS gm ON gm.group_id = g.id AND gm.deleted_at = 0 JOIN users AS u ON u.id = gm.user_id WHERE g.uuid = ? AND g.deleted_at = 0 AND (g.user_id = gm.user_id OR gm.role = ?)",
+		groupUuid, constant.GROUP_ROLE_ADMIN).Scan(&uuids)
+	return uuids
+}
+
+// SetJoinPolicy 函数用于群主或管理员修改群组的加群方式
+func (g *groupService) SetJoinPolicy(groupUuid, operatorUuid string, joinPolicy int16) error {
+	if joinPolicy < constant.GROUP_JOIN_OPEN || joinPolicy > constant.GROUP_JOIN_INVITE {
+		return errors.New("不支持的加群方式")
+	}
+	db := pool.GetDB()
+	group, _, err := groupAdmin(db, groupUuid, operatorUuid)
+	if err != nil {
+		return err
+	}
+
+	db.Model(&group).Update("join_policy", joinPolicy)
+	return nil
+}
+
+// CreateInvite 函数用于群主或管理员创建邀请链接，expire为有效时长（秒），0表示永不过期，maxUses为0表示不限次数
+func (g *groupService) CreateInvite(groupUuid, operatorUuid string, expire int64, maxUses int32) (*response.GroupInviteResponse, error) {
+	if expire < 0 || maxUses < 0 {
+		return nil, errors.New("邀请链接的有效期或使用次数不正确")
+	}
+	db := pool.GetDB()
+	db.AutoMigrate(&model.GroupInvite{}) // 自动迁移邀请链接表结构，确保表存在
+	group, operator, err := groupAdmin(db, groupUuid, operatorUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := model.GroupInvite{
+		Code:    uuid.New().String(),
+		GroupId: group.ID,
+		UserId:  operator.Id,
+		MaxUses: maxUses,
+	}
+	if expire > 0 {
+		invite.ExpiresAt = time.Now().Add(time.Duration(expire)*time.Second).UnixNano() / int64(time.Millisecond)
+	}
+	if err := db.Create(&invite).Error; err != nil {
+		return nil, errors.New("创建邀请链接失败")
+	}
+	return inviteResponse(group, invite), nil
+}
+
+// GetInvites 函数用于群主或管理员查看群组仍然可以使用的邀请链接
+func (g *groupService) GetInvites(groupUuid, operatorUuid string) ([]*response.GroupInviteResponse, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.GroupInvite{}) // 自动迁移邀请链接表结构，确保表存在
+	group, _, err := groupAdmin(db, groupUuid, operatorUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var invites []model.GroupInvite
+	db.Where("group_id = ?", group.ID).Order("id DESC").Find(&invites)
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	responses := make([]*response.GroupInviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		if invite.Usable(now) {
+			responses = append(responses, inviteResponse(group, invite))
+		}
+	}
+	return responses, nil
+}
+
+// RevokeInvite 函数用于群主或管理员撤销邀请链接，撤销后的邀请码不能再使用
+func (g *groupService) RevokeInvite(groupUuid, operatorUuid, code string) error {
+	db := pool.GetDB()
+	group, _, err := groupAdmin(db, groupUuid, operatorUuid)
+	if err != nil {
+		return err
+	}
+
+	var invite model.GroupInvite
+	db.First(&invite, "code = ? AND group_id = ?", code, group.ID)
+	if invite.ID <= 0 {
+		return errors.New("邀请链接不存在")
+	}
+	db.Delete(&invite) // 逻辑删除邀请链接
+	return nil
+}
+
+// JoinByInvite 函数用于用户通过邀请码加入群组，不受加群方式限制，返回加入的群组uuid
+func (g *groupService) JoinByInvite(code, userUuid string) (string, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.GroupInvite{}) // 自动迁移邀请链接表结构，确保表存在
+	var invite model.GroupInvite
+	db.First(&invite, "code = ?", code)
+	if invite.ID <= 0 || !invite.Usable(time.Now().UnixNano()/int64(time.Millisecond)) {
+		return "", errors.New("邀请链接已失效")
+	}
+
+	var group model.Group
+	db.First(&group, "id = ?", invite.GroupId)
+	if group.ID <= 0 {
+		return "", errors.New("群组不存在")
+	}
+	var user model.User
+	db.First(&user, "uuid = ?", userUuid)
+	if user.Id <= 0 {
+		return "", errors.New("用户不存在")
+	}
+	if isGroupMember(db, group.ID, user.Id) {
+		return "", errors.New("已经加入该群组")
+	}
+
+	// 使用次数在同一个事务中按条件递增，并发使用同一个邀请码时不会超过最大次数
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.GroupInvite{}).
+			Where("id = ? AND (max_uses = 0 OR uses < max_uses)", invite.ID).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return errors.New("加入群组失败")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("邀请链接已失效")
+		}
+		return addGroupMember(tx, group, user)
+	})
+	if err != nil {
+		return "", err
+	}
+	invalidateGroupMembers(group.Uuid)
+	return group.Uuid, nil
+}
+
+// GetJoinRequests 函数用于群主或管理员查看群组待审核的加群申请
+func (g *groupService) GetJoinRequests(groupUuid, operatorUuid string) ([]response.GroupJoinRequestResponse, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.GroupJoinRequest{}) // 自动迁移加群申请表结构，确保表存在
+	group, _, err := groupAdmin(db, groupUuid, operatorUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var requests []response.GroupJoinRequestResponse
+	db.Raw("SELECT r.id, ? AS group_uuid, u.uuid, u.username, u.nickname, u.avatar, r.reason, r.status, r.created_at FROM group_join_requests AS r JOIN users AS u ON u.id = r.user_id WHERE r.group_id = ? AND r.status = ? ORDER BY r.id",
+		group.Uuid, group.ID, constant.GROUP_REQUEST_PENDING).Scan(&requests)
+	return requests, nil
+}
+
+// ApproveJoinRequest 函数用于群主或管理员通过加群申请，申请人加入群组，返回申请人的uuid
+// joined表示申请人是否因本次审核加入群组，申请人已通过邀请链接加入时为false，调用者不需要再发布加入通知
+func (g *groupService) ApproveJoinRequest(groupUuid, operatorUuid string, requestId int32) (applicantUuid string, joined bool, err error) {
+	db := pool.GetDB()
+	group, operator, joinRequest, applicant, err := pendingJoinRequest(db, groupUuid, operatorUuid, requestId)
+	if err != nil {
+		return "", false, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&joinRequest).Where("status = ?", constant.GROUP_REQUEST_PENDING).
+			Updates(map[string]interface{}{"status": constant.GROUP_REQUEST_APPROVED, "operator_id": operator.Id})
+		if result.Error != nil {
+			return errors.New("处理加群申请失败")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("加群申请已处理")
+		}
+		if isGroupMember(tx, group.ID, applicant.Id) { // 申请人已通过邀请链接加入
+			return nil
+		}
+		joined = true
+		return addGroupMember(tx, group, applicant)
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if joined {
+		invalidateGroupMembers(groupUuid)
+	}
+	return applicant.Uuid, joined, nil
+}
+
+// RejectJoinRequest 函数用于群主或管理员拒绝加群申请，返回申请人的uuid
+func (g *groupService) RejectJoinRequest(groupUuid, operatorUuid string, requestId int32) (string, error) {
+	db := pool.GetDB()
+	_, operator, joinRequest, applicant, err := pendingJoinRequest(db, groupUuid, operatorUuid, requestId)
+	if err != nil {
+		return "", err
+	}
+
+	result := db.Model(&joinRequest).Where("status = ?", constant.GROUP_REQUEST_PENDING).
+		Updates(map[string]interface{}{"status": constant.GROUP_REQUEST_REJECTED, "operator_id": operator.Id})
+	if result.Error != nil {
+		return "", errors.New("处理加群申请失败")
+	}
+	if result.RowsAffected == 0 {
+		return "", errors.New("加群申请已处理")
+	}
+	return applicant.Uuid, nil
+}
+
+// pendingJoinRequest 函数查询群组中待审核的加群申请及其申请人，操作者必须是群主或管理员
+func pendingJoinRequest(db *gorm.DB, groupUuid, operatorUuid string, requestId int32) (model.Group, model.User, model.GroupJoinRequest, model.User, error) {
+	var joinRequest model.GroupJoinRequest
+	var applicant model.User
+	group, operator, err := groupAdmin(db, groupUuid, operatorUuid)
+	if err != nil {
+		return group, operator, joinRequest, applicant, err
+	}
+
+	db.First(&joinRequest, "id = ? AND group_id = ?", requestId, group.ID)
+	if joinRequest.ID <= 0 {
+		return group, operator, joinRequest, applicant, errors.New("加群申请不存在")
+	}
+	if joinRequest.Status != constant.GROUP_REQUEST_PENDING {
+		return group, operator, joinRequest, applicant, errors.New("加群申请已处理")
+	}
+	db.First(&applicant, "id = ?", joinRequest.UserId)
+	if applicant.Id <= 0 {
+		return group, operator, joinRequest, applicant, errors.New("用户不存在")
+	}
+	return group, operator, joinRequest, applicant, nil
+}
+
+// inviteResponse 函数把邀请链接转换为响应结构
+func inviteResponse(group model.Group, invite model.GroupInvite) *response.GroupInviteResponse {
+	return &response.GroupInviteResponse{
+		Code:      invite.Code,
+		GroupUuid: group.Uuid,
+		CreatedAt: invite.CreatedAt,
+		ExpiresAt: invite.ExpiresAt,
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+	}
+}
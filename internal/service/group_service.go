@@ -3,9 +3,13 @@ package service
 import (
 	"chat-room/internal/dao/pool"   // 引入数据库连接池
 	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/pkg/common/constant" // 引入常量包，定义了群成员角色
 	"chat-room/pkg/common/response" // 引入通用响应包，用于定义响应结构
 	"chat-room/pkg/errors"          // 引入自定义错误包
-	"github.com/google/uuid"        // 引入UUID库，用于生成唯一标识符
+	"time"                          // 引入时间包，用于计算禁言的截止时间
+
+	"github.com/google/uuid" // 引入UUID库，用于生成唯一标识符
+	"gorm.io/gorm"           // 引入GORM，用于在事务中修改群组和成员
 )
 
 // groupService 结构体定义了群组服务的实现
@@ -35,7 +39,7 @@ func (g *groupService) GetGroups(uuid string) ([]response.GroupResponse, error)
 	var groups []response.GroupResponse
 
 	// 使用原生SQL查询用户所属的群组信息
-	db.Raw("SELECT g.id AS group_id, g.uuid, g.created_at, g.name, g.notice FROM group_members AS gm LEFT JOIN `groups` AS g ON gm.group_id = g.id WHERE gm.user_id = ?",
+	db.Raw("SELECT g.id AS group_id, g.uuid, g.created_at, g.name, g.notice, g.mute_all, g.join_policy FROM group_members AS gm JOIN `groups` AS g ON gm.group_id = g.id AND g.deleted_at = 0 WHERE gm.user_id = ? AND gm.deleted_at = 0",
 		queryUser.Id).Scan(&groups)
 
 	return groups, nil // 返回群组列表
@@ -52,66 +56,459 @@ func (g *groupService) SaveGroup(userUuid string, group model.Group) {
 
 	// 设置群组的创建者ID和UUID
 	group.UserId = fromUser.Id
+	if group.JoinPolicy < constant.GROUP_JOIN_OPEN || group.JoinPolicy > constant.GROUP_JOIN_INVITE {
+		group.JoinPolicy = constant.GROUP_JOIN_OPEN // 不支持的加群方式按直接加入处理
+	}
 	group.Uuid = uuid.New().String()
 	db.Save(&group) // 保存群组信息
 
-	// 创建群组成员记录，将创建者加入群组
+	// 创建群组成员记录，将创建者作为群主加入群组
 	groupMember := model.GroupMember{
 		UserId:   fromUser.Id,
 		GroupId:  group.ID,
 		Nickname: fromUser.Username,
 		Mute:     0,
+		Role:     constant.GROUP_ROLE_OWNER,
 	}
 	db.Save(&groupMember) // 保存群组成员信息
+	invalidateGroupMembers(group.Uuid)
 }
 
-// GetUserIdByGroupUuid 函数根据群组的UUID获取群组内的用户列表
-func (g *groupService) GetUserIdByGroupUuid(groupUuid string) []model.User {
-	var group model.Group
-	db := pool.GetDB()                      // 获取数据库连接实例
-	db.First(&group, "uuid = ?", groupUuid) // 根据群组UUID查询群组信息
-	if group.ID <= 0 {
-		return nil // 如果群组不存在，返回空列表
+// GetGroupMembers 函数根据群组的UUID获取群组内的成员及其角色，群主以群组的UserId为准
+func (g *groupService) GetGroupMembers(groupUuid string) []response.GroupMemberResponse {
+	pool.GetDB().AutoMigrate(&model.GroupMember{}) // 自动迁移成员表结构，确保角色和禁言字段存在
+
+	var members []response.GroupMemberResponse
+	pool.GetDB().Raw("SELECT u.uuid, u.username, u.nickname, u.avatar, IF(g.user_id = gm.user_id, ?, gm.role) AS role, gm.mute, gm.mute_until FROM `groups` AS g JOIN group_members AS gm ON gm.group_id = g.id AND gm.deleted_at = 0 JOIN users AS u ON u.id = gm.user_id WHERE g.uuid = ? AND g.deleted_at = 0 ORDER BY role DESC, gm.id",
+		constant.GROUP_ROLE_OWNER, groupUuid).Scan(&members)
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	for i := range members {
+		member := model.GroupMember{Mute: members[i].Mute, MuteUntil: members[i].MuteUntil}
+		if !member.Muted(now) { // 限时禁言已到期的成员按未禁言返回
+			members[i].Mute = 0
+			members[i].MuteUntil = 0
+		}
+	}
+	return members
+}
+
+// GetGroupMemberUuids 函数根据群组的UUID获取群组内所有成员的uuid，用于消息扇出
+// 未命中缓存时通过一次查询获取成员及其资料，同时写入成员列表和用户资料缓存
+func (g *groupService) GetGroupMemberUuids(groupUuid string) []string {
+	var uuids []string
+	if getCached(GROUP_MEMBERS_CACHE_KEY+groupUuid, &uuids) {
+		return uuids
 	}
 
 	var users []model.User
 	// 使用原生SQL查询群组成员信息
-	db.Raw("SELECT u.uuid, u.avatar, u.username FROM `groups` AS g JOIN group_members AS gm ON gm.group_id = g.id JOIN users AS u ON u.id = gm.user_id WHERE g.id = ?",
-		group.ID).Scan(&users)
-	return users // 返回用户列表
+	pool.GetDB().Raw("SELECT u.uuid, u.username, u.nickname, u.avatar FROM `groups` AS g JOIN group_members AS gm ON gm.group_id = g.id AND gm.deleted_at = 0 JOIN users AS u ON u.id = gm.user_id WHERE g.uuid = ? AND g.deleted_at = 0",
+		groupUuid).Scan(&users)
+
+	uuids = make([]string, 0, len(users))
+	for _, user := range users {
+		uuids = append(uuids, user.Uuid)
+		setCached(USER_PROFILE_CACHE_KEY+user.Uuid, user)
+	}
+	setCached(GROUP_MEMBERS_CACHE_KEY+groupUuid, uuids)
+	return uuids
+}
+
+// IsGroupMember 函数判断用户是否为群组当前的成员，使用缓存的成员列表，用于校验已读回执和正在输入等不持久化的群消息
+func (g *groupService) IsGroupMember(groupUuid, userUuid string) bool {
+	for _, member := range g.GetGroupMemberUuids(groupUuid) {
+		if member == userUuid {
+			return true
+		}
+	}
+	return false
 }
 
-// JoinGroup 函数用于将用户加入到指定的群组
-func (g *groupService) JoinGroup(groupUuid, userUuid string) error {
+// JoinGroup 函数用于用户申请加入指定的群组，按群组的加群方式处理
+// 直接加入的群组立即加入并返回nil；需要审核的群组创建待审核的加群申请并返回该申请；只能通过邀请加入的群组返回错误
+func (g *groupService) JoinGroup(groupUuid, userUuid, reason string) (*response.GroupJoinRequestResponse, error) {
+	db := pool.GetDB()                                        // 获取数据库连接实例
+	db.AutoMigrate(&model.Group{}, &model.GroupJoinRequest{}) // 自动迁移群组和加群申请表结构，确保加群方式字段存在
 	var user model.User
-	db := pool.GetDB()                    // 获取数据库连接实例
 	db.First(&user, "uuid = ?", userUuid) // 根据用户UUID查询用户信息
 	if user.Id <= 0 {
-		return errors.New("用户不存在") // 如果用户不存在，返回错误
+		return nil, errors.New("用户不存在") // 如果用户不存在，返回错误
 	}
 
 	var group model.Group
 	db.First(&group, "uuid = ?", groupUuid) // 根据群组UUID查询群组信息
 	if group.ID <= 0 {
-		return errors.New("群组不存在") // 如果群组不存在，返回错误
+		return nil, errors.New("群组不存在") // 如果群组不存在，返回错误
 	}
-	var groupMember model.GroupMember
-	db.First(&groupMember, "user_id = ? and group_id = ?", user.Id, group.ID) // 检查用户是否已经是群组成员
-	if groupMember.ID > 0 {
-		return errors.New("已经加入该群组") // 如果已经加入群组，返回错误
+	if isGroupMember(db, group.ID, user.Id) {
+		return nil, errors.New("已经加入该群组") // 如果已经加入群组，返回错误
+	}
+
+	switch group.JoinPolicy {
+	case constant.GROUP_JOIN_OPEN:
+		if err := addGroupMember(db, group, user); err != nil {
+			return nil, err
+		}
+		invalidateGroupMembers(groupUuid)
+		return nil, nil
+	case constant.GROUP_JOIN_APPROVAL:
+		var pending model.GroupJoinRequest
+		db.First(&pending, "group_id = ? AND user_id = ? AND status = ?", group.ID, user.Id, constant.GROUP_REQUEST_PENDING)
+		if pending.ID > 0 {
+			return nil, errors.New("已提交加群申请，请等待审核")
+		}
+		joinRequest := model.GroupJoinRequest{
+			GroupId: group.ID,
+			UserId:  user.Id,
+			Reason:  reason,
+			Status:  constant.GROUP_REQUEST_PENDING,
+		}
+		if err := db.Create(&joinRequest).Error; err != nil {
+			return nil, errors.New("提交加群申请失败")
+		}
+		return &response.GroupJoinRequestResponse{
+			Id:        joinRequest.ID,
+			GroupUuid: group.Uuid,
+			Uuid:      user.Uuid,
+			Username:  user.Username,
+			Nickname:  user.Nickname,
+			Avatar:    user.Avatar,
+			Reason:    joinRequest.Reason,
+			Status:    joinRequest.Status,
+			CreatedAt: joinRequest.CreatedAt,
+		}, nil
+	default:
+		return nil, errors.New("该群组只能通过邀请链接加入")
 	}
+}
+
+// isGroupMember 函数判断用户是否为群组的成员
+func isGroupMember(db *gorm.DB, groupId, userId int32) bool {
+	var groupMember model.GroupMember
+	db.Select("id").Find(&groupMember, "user_id = ? AND group_id = ?", userId, groupId)
+	return groupMember.ID > 0
+}
+
+// addGroupMember 函数将用户加入群组，并把该用户在这个群组中待审核的加群申请标记为已通过
+// db可以是事务，调用者需要先确认用户还不是群组成员，并在提交后使群成员缓存失效
+func addGroupMember(db *gorm.DB, group model.Group, user model.User) error {
 	nickname := user.Nickname
 	if nickname == "" { // 如果用户没有设置昵称，则使用用户名作为昵称
 		nickname = user.Username
 	}
 	// 创建新的群组成员记录
-	groupMemberInsert := model.GroupMember{
+	groupMember := model.GroupMember{
 		UserId:   user.Id,
 		GroupId:  group.ID,
 		Nickname: nickname,
 		Mute:     0,
 	}
-	db.Save(&groupMemberInsert) // 保存群组成员信息
+	if err := db.Create(&groupMember).Error; err != nil { // 保存群组成员信息
+		return errors.New("加入群组失败")
+	}
+	db.Model(&model.GroupJoinRequest{}).
+		Where("group_id = ? AND user_id = ? AND status = ?", group.ID, user.Id, constant.GROUP_REQUEST_PENDING).
+		Update("status", constant.GROUP_REQUEST_APPROVED)
+	return nil
+}
+
+// groupMembership 函数查询群组、用户以及用户在群组中的成员记录，用户不是群成员时返回错误
+// 群主以群组的UserId为准，成员记录中的角色可能是升级前的默认值
+func groupMembership(db *gorm.DB, groupUuid, userUuid string) (model.Group, model.User, model.GroupMember, error) {
+	db.AutoMigrate(&model.Group{}, &model.GroupMember{}) // 自动迁移群组和成员表结构，确保角色和禁言字段存在
+
+	var group model.Group
+	var user model.User
+	var member model.GroupMember
+	db.First(&group, "uuid = ?", groupUuid)
+	if group.ID <= 0 {
+		return group, user, member, errors.New("群组不存在")
+	}
+	db.First(&user, "uuid = ?", userUuid)
+	if user.Id <= 0 {
+		return group, user, member, errors.New("用户不存在")
+	}
+	db.First(&member, "group_id = ? AND user_id = ?", group.ID, user.Id)
+	if member.ID <= 0 {
+		return group, user, member, errors.New("不是群组成员")
+	}
+	member.Role = memberRole(group, member)
+	return group, user, member, nil
+}
+
+// memberRole 函数返回成员在群组中的实际角色，群主以群组的UserId为准
+func memberRole(group model.Group, member model.GroupMember) int16 {
+	if group.UserId == member.UserId {
+		return constant.GROUP_ROLE_OWNER
+	}
+	if member.Role == constant.GROUP_ROLE_OWNER {
+		return constant.GROUP_ROLE_MEMBER // 群主已转让，旧记录按普通成员处理
+	}
+	return member.Role
+}
+
+// checkSpeak 函数判断成员当前能否在群组中发言：被禁言且未到期的成员不能发言，开启全员禁言时只有群主和管理员可以发言
+func checkSpeak(group model.Group, member model.GroupMember) error {
+	if member.Muted(time.Now().UnixNano() / int64(time.Millisecond)) {
+		return errors.New("你已被禁言")
+	}
+	if group.MuteAll == 1 && memberRole(group, member) < constant.GROUP_ROLE_ADMIN {
+		return errors.New("群组已开启全员禁言")
+	}
+	return nil
+}
+
+// CheckSpeak 函数在转发群消息前检查发送者是否为群成员并且没有被禁言，使用缓存的发言状态
+func (g *groupService) CheckSpeak(groupUuid, userUuid string) error {
+	group, member, err := speakingMember(groupUuid, userUuid)
+	if err != nil {
+		return err
+	}
+	return checkSpeak(group, member)
+}
+
+// groupSpeakState 结构体表示缓存的群组发言状态，群组只包含ID、群主和全员禁言，成员以用户uuid为键
+type groupSpeakState struct {
+	Group   model.Group                  `json:"group"`   // 群组的ID、群主ID和全员禁言
+	Members map[string]model.GroupMember `json:"members"` // 成员的ID、用户ID、角色和禁言状态
+}
+
+// speakingMember 函数获取群组和发送者的成员记录，用于检查发言权限，发送群消息时不需要查询数据库
+// 发言状态未命中缓存时通过两次查询获取群组和所有成员，群组不存在时不写入缓存
+func speakingMember(groupUuid, userUuid string) (model.Group, model.GroupMember, error) {
+	var state groupSpeakState
+	if !getCached(GROUP_SPEAK_CACHE_KEY+groupUuid, &state) {
+		db := pool.GetDB()
+		db.Select("id", "user_id", "mute_all").Find(&state.Group, "uuid = ?", groupUuid)
+		if state.Group.ID <= 0 {
+			return state.Group, model.GroupMember{}, errors.New("群组不存在")
+		}
+		var members []struct {
+			Uuid string
+			model.GroupMember
+		}
+		db.Raw("SELECT u.uuid, gm.id, gm.user_id, gm.group_id, gm.role, gm.mute, gm.mute_until FROM group_members AS gm JOIN users AS u ON u.id = gm.user_id WHERE gm.group_id = ? AND gm.deleted_at = 0",
+			state.Group.ID).Scan(&members)
+		state.Members = make(map[string]model.GroupMember, len(members))
+		for _, member := range members {
+			state.Members[member.Uuid] = member.GroupMember
+		}
+		setCached(GROUP_SPEAK_CACHE_KEY+groupUuid, state)
+	}
+
+	member, ok := state.Members[userUuid]
+	if !ok {
+		return state.Group, member, errors.New("不是群组成员")
+	}
+	return state.Group, member, nil
+}
+
+// LeaveGroup 函数用于用户退出群组，群主需要先转让群主或解散群组
+func (g *groupService) LeaveGroup(groupUuid, userUuid string) error {
+	db := pool.GetDB()
+	_, _, member, err := groupMembership(db, groupUuid, userUuid)
+	if err != nil {
+		return err
+	}
+	if member.Role == constant.GROUP_ROLE_OWNER {
+		return errors.New("群主不能退出群组，请先转让群主或解散群组")
+	}
+
+	db.Delete(&member) // 逻辑删除成员记录
+	invalidateGroupMembers(groupUuid)
+	return nil
+}
+
+// KickMember 函数用于将成员移出群组，群主可以移出任何成员，管理员只能移出普通成员
+func (g *groupService) KickMember(groupUuid, operatorUuid, memberUuid string) error {
+	db := pool.GetDB()
+	_, _, operator, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return err
+	}
+	_, _, member, err := groupMembership(db, groupUuid, memberUuid)
+	if err != nil {
+		return err
+	}
+	if operator.ID == member.ID {
+		return errors.New("不能移出自己，请使用退出群组")
+	}
+	if operator.Role < constant.GROUP_ROLE_ADMIN || operator.Role <= member.Role {
+		return errors.New("没有权限移出该成员")
+	}
+
+	db.Delete(&member) // 逻辑删除成员记录
+	invalidateGroupMembers(groupUuid)
+	return nil
+}
+
+// SetMemberRole 函数用于群主设置或取消管理员，role只能是管理员或普通成员
+func (g *groupService) SetMemberRole(groupUuid, operatorUuid, memberUuid string, role int16) error {
+	if role != constant.GROUP_ROLE_ADMIN && role != constant.GROUP_ROLE_MEMBER {
+		return errors.New("不支持的成员角色")
+	}
+	db := pool.GetDB()
+	_, _, operator, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return err
+	}
+	if operator.Role != constant.GROUP_ROLE_OWNER {
+		return errors.New("只有群主可以设置管理员")
+	}
+	_, _, member, err := groupMembership(db, groupUuid, memberUuid)
+	if err != nil {
+		return err
+	}
+	if member.Role == constant.GROUP_ROLE_OWNER {
+		return errors.New("不能修改群主的角色")
+	}
+	if member.Role == role {
+		return errors.New("成员已经是该角色")
+	}
+
+	db.Model(&member).Update("role", role)
+	invalidateGroupMembers(groupUuid)
+	return nil
+}
+
+// TransferOwner 函数用于群主将群组转让给其他成员，原群主成为普通成员
+func (g *groupService) TransferOwner(groupUuid, operatorUuid, memberUuid string) error {
+	db := pool.GetDB()
+	group, _, operator, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return err
+	}
+	if operator.Role != constant.GROUP_ROLE_OWNER {
+		return errors.New("只有群主可以转让群组")
+	}
+	_, newOwner, member, err := groupMembership(db, groupUuid, memberUuid)
+	if err != nil {
+		return err
+	}
+	if operator.ID == member.ID {
+		return errors.New("已经是群主")
+	}
+
+	// 群组的群主和两条成员记录的角色在同一个事务中修改
+	defer invalidateGroupMembers(groupUuid)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&group).Update("user_id", newOwner.Id).Error; err != nil {
+			return errors.New("转让群组失败")
+		}
+		if err := tx.Model(&operator).Update("role", constant.GROUP_ROLE_MEMBER).Error; err != nil {
+			return errors.New("转让群组失败")
+		}
+		if err := tx.Model(&member).Update("role", constant.GROUP_ROLE_OWNER).Error; err != nil {
+			return errors.New("转让群组失败")
+		}
+		return nil
+	})
+}
+
+// DissolveGroup 函数用于群主解散群组，逻辑删除群组和所有成员记录，返回解散前的成员uuid用于通知
+func (g *groupService) DissolveGroup(groupUuid, operatorUuid string) ([]string, error) {
+	db := pool.GetDB()
+	group, _, operator, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return nil, err
+	}
+	if operator.Role != constant.GROUP_ROLE_OWNER {
+		return nil, errors.New("只有群主可以解散群组")
+	}
+
+	members := g.GetGroupMemberUuids(groupUuid)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", group.ID).Delete(&model.GroupMember{}).Error; err != nil {
+			return errors.New("解散群组失败")
+		}
+		if err := tx.Delete(&group).Error; err != nil {
+			return errors.New("解散群组失败")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	invalidateGroupMembers(groupUuid)
+	return members, nil
+}
+
+// MuteMember 函数用于禁言成员，duration为禁言时长（秒），0表示永久禁言
+// 群主可以禁言任何成员，管理员只能禁言普通成员
+func (g *groupService) MuteMember(groupUuid, operatorUuid, memberUuid string, duration int64) error {
+	if duration < 0 {
+		return errors.New("禁言时长不正确")
+	}
+	db := pool.GetDB()
+	member, err := g.manageableMember(db, groupUuid, operatorUuid, memberUuid)
+	if err != nil {
+		return err
+	}
+
+	var muteUntil int64 = 0
+	if duration > 0 {
+		muteUntil = time.Now().Add(time.Duration(duration)*time.Second).UnixNano() / int64(time.Millisecond)
+	}
+	db.Model(&member).Updates(map[string]interface{}{"mute": 1, "mute_until": muteUntil})
+	invalidateGroupMembers(groupUuid)
+	return nil
+}
+
+// UnmuteMember 函数用于解除成员的禁言，权限要求与禁言相同
+func (g *groupService) UnmuteMember(groupUuid, operatorUuid, memberUuid string) error {
+	db := pool.GetDB()
+	member, err := g.manageableMember(db, groupUuid, operatorUuid, memberUuid)
+	if err != nil {
+		return err
+	}
+	if !member.Muted(time.Now().UnixNano() / int64(time.Millisecond)) {
+		return errors.New("成员未被禁言")
+	}
+
+	db.Model(&member).Updates(map[string]interface{}{"mute": 0, "mute_until": 0})
+	invalidateGroupMembers(groupUuid)
+	return nil
+}
+
+// manageableMember 函数查询操作者可以管理的成员记录，操作者必须是群主或管理员，并且角色高于该成员
+func (g *groupService) manageableMember(db *gorm.DB, groupUuid, operatorUuid, memberUuid string) (model.GroupMember, error) {
+	_, _, operator, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return operator, err
+	}
+	_, _, member, err := groupMembership(db, groupUuid, memberUuid)
+	if err != nil {
+		return member, err
+	}
+	if operator.ID == member.ID {
+		return member, errors.New("不能对自己执行该操作")
+	}
+	if operator.Role < constant.GROUP_ROLE_ADMIN || operator.Role <= member.Role {
+		return member, errors.New("没有权限管理该成员")
+	}
+	return member, nil
+}
+
+// SetMuteAll 函数用于群主或管理员开启或关闭全员禁言
+func (g *groupService) SetMuteAll(groupUuid, operatorUuid string, muteAll bool) error {
+	db := pool.GetDB()
+	group, _, operator, err := groupMembership(db, groupUuid, operatorUuid)
+	if err != nil {
+		return err
+	}
+	if operator.Role < constant.GROUP_ROLE_ADMIN {
+		return errors.New("只有群主和管理员可以设置全员禁言")
+	}
+	var value int16 = 0
+	if muteAll {
+		value = 1
+	}
+	if group.MuteAll == value {
+		return errors.New("全员禁言状态未变化")
+	}
 
-	return nil // 返回成功
+	db.Model(&group).Update("mute_all", value)
+	invalidateGroupMembers(groupUuid)
+	return nil
 }
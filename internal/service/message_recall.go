@@ -0,0 +1,224 @@
+package service
+
+import (
+	"chat-room/config"              // 引入配置包，用于读取撤回时间窗口
+	"chat-room/internal/dao/pool"   // 引入数据库连接池
+	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/internal/search"     // 引入消息全文检索包，用于更新编辑后的索引
+	"chat-room/pkg/common/constant" // 引入全局常量
+	"chat-room/pkg/common/response" // 引入通用响应包
+	"chat-room/pkg/errors"          // 引入自定义错误处理包
+	"chat-room/pkg/protocol"        // 引入消息协议包，用于构造撤回和编辑通知
+	"strings"                       // 引入字符串处理包，用于检查编辑后的内容
+	"time"                          // 引入时间包，用于判断撤回时间窗口
+	"unicode/utf8"                  // 引入utf8包，用于检查编辑后内容的长度
+
+	"gorm.io/gorm" // 引入GORM ORM库
+)
+
+const (
+	DEFAULT_RECALL_WINDOW = 2 * time.Minute // 没有配置时发送者可以撤回消息的时间窗口
+	MAX_CONTENT_LENGTH    = 2500            // 消息内容的最大字符数，与messages表content字段的长度一致
+)
+
+// RecallMessage 函数用于撤回消息，撤回后清空消息内容和编辑历史，返回需要发布的撤回通知
+// 发送者可以在撤回时间窗口内撤回自己的消息，群主和管理员可以随时撤回角色低于自己的成员发送的群消息
+func (m *messageService) RecallMessage(operatorUuid string, messageId int32) (*protocol.Message, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.Message{}, &model.MessageEdit{}) // 自动迁移消息和编辑历史表结构，确保撤回和编辑字段存在
+	message, operator, err := findMessage(db, operatorUuid, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.FromUserId == operator.Id {
+		window := time.Duration(config.GetConfig().Message.RecallWindow) * time.Second
+		if window <= 0 {
+			window = DEFAULT_RECALL_WINDOW
+		}
+		if time.Since(message.CreatedAt) > window {
+			return nil, errors.New("消息已超过可撤回的时间")
+		}
+	} else if message.MessageType == constant.MESSAGE_TYPE_GROUP {
+		if err := checkGroupRecall(db, message, operator); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("只能撤回自己发送的消息")
+	}
+
+	recalledAt := time.Now().UnixNano() / int64(time.Millisecond)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&message).Where("recalled_at = 0").
+			Updates(map[string]interface{}{"content": "", "url": "", "recalled_at": recalledAt})
+		if result.Error != nil {
+			return errors.New("撤回消息失败")
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("消息已撤回")
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&model.MessageEdit{}).Error; err != nil {
+			return errors.New("撤回消息失败")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	messageSearchIndex.Remove(message.ID)
+	return messageEvent(db, constant.RECALL, operator, message, "", recalledAt)
+}
+
+// EditMessage 函数用于发送者编辑自己发送的文字消息，编辑前的内容保存到编辑历史，返回需要发布的编辑通知
+func (m *messageService) EditMessage(operatorUuid string, messageId int32, content string) (*protocol.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.New("消息内容不能为空")
+	}
+	if utf8.RuneCountInString(content) > MAX_CONTENT_LENGTH {
+		return nil, errors.New("消息内容过长")
+	}
+	db := pool.GetDB()
+	db.AutoMigrate(&model.Message{}, &model.MessageEdit{}) // 自动迁移消息和编辑历史表结构，确保撤回和编辑字段存在
+	message, operator, err := findMessage(db, operatorUuid, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if message.FromUserId != operator.Id {
+		return nil, errors.New("只能编辑自己发送的消息")
+	}
+	if message.ContentType != constant.TEXT {
+		return nil, errors.New("只能编辑文字消息")
+	}
+	if message.Content == content {
+		return nil, errors.New("消息内容没有变化")
+	}
+	if message.MessageType == constant.MESSAGE_TYPE_GROUP { // 退出群组或被禁言后不能再编辑群消息
+		var group model.Group
+		db.First(&group, "id = ?", message.ToUserId)
+		var member model.GroupMember
+		db.Find(&member, "group_id = ? AND user_id = ?", message.ToUserId, operator.Id)
+		if group.ID == NULL_ID || member.ID == NULL_ID {
+			return nil, errors.New("不是群组成员")
+		}
+		if err := checkSpeak(group, member); err != nil {
+			return nil, err
+		}
+	}
+
+	editedAt := time.Now().UnixNano() / int64(time.Millisecond)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&model.MessageEdit{MessageId: message.ID, Content: message.Content}).Error; err != nil {
+			return errors.New("编辑消息失败")
+		}
+		result := tx.Model(&message).Where("recalled_at = 0 AND content = ?", message.Content).
+			Updates(map[string]interface{}{"content": content, "edited_at": editedAt})
+		if result.Error != nil {
+			return errors.New("编辑消息失败")
+		}
+		if result.RowsAffected == 0 { // 消息在此期间被撤回或编辑
+			return errors.New("消息已被修改，请刷新后重试")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messageSearchIndex.Remove(message.ID)
+	messageSearchIndex.Index(search.Document{
+		MessageId:   message.ID,
+		MessageType: int32(message.MessageType),
+		FromUserId:  message.FromUserId,
+		ToUserId:    message.ToUserId,
+		Content:     content,
+		CreatedAt:   message.CreatedAt,
+	})
+	return messageEvent(db, constant.EDIT, operator, message, content, editedAt)
+}
+
+// GetMessageHistory 函数获取消息的编辑历史，按编辑时间升序排列，只有会话的参与者可以查看
+func (m *messageService) GetMessageHistory(userUuid string, messageId int32) ([]response.MessageEditResponse, error) {
+	db := pool.GetDB()
+	db.AutoMigrate(&model.MessageEdit{}) // 自动迁移编辑历史表结构，确保表存在
+	message, user, err := findMessage(db, userUuid, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if message.MessageType == constant.MESSAGE_TYPE_USER && message.FromUserId != user.Id && message.ToUserId != user.Id {
+		return nil, errors.New("消息不存在")
+	}
+	if message.MessageType == constant.MESSAGE_TYPE_GROUP && !isGroupMember(db, message.ToUserId, user.Id) {
+		return nil, errors.New("消息不存在")
+	}
+
+	var edits []response.MessageEditResponse
+	db.Model(&model.MessageEdit{}).Select("content", "created_at").Where("message_id = ?", message.ID).Order("id").Scan(&edits)
+	return edits, nil
+}
+
+// findMessage 函数查询消息和操作者，已撤回的消息不能再撤回或编辑
+func findMessage(db *gorm.DB, operatorUuid string, messageId int32) (model.Message, model.User, error) {
+	var message model.Message
+	var operator model.User
+	db.First(&operator, "uuid = ?", operatorUuid)
+	if NULL_ID == operator.Id {
+		return message, operator, errors.New("用户不存在")
+	}
+	db.First(&message, "id = ?", messageId)
+	if NULL_ID == message.ID {
+		return message, operator, errors.New("消息不存在")
+	}
+	if message.RecalledAt > 0 {
+		return message, operator, errors.New("消息已撤回")
+	}
+	return message, operator, nil
+}
+
+// checkGroupRecall 函数判断操作者能否撤回群组中其他成员发送的消息，操作者必须是群主或管理员，并且角色高于发送者
+func checkGroupRecall(db *gorm.DB, message model.Message, operator model.User) error {
+	var group model.Group
+	db.First(&group, "id = ?", message.ToUserId)
+	if NULL_ID == group.ID {
+		return errors.New("群组不存在")
+	}
+	var operatorMember, senderMember model.GroupMember
+	db.Find(&operatorMember, "group_id = ? AND user_id = ?", group.ID, operator.Id)
+	if NULL_ID == operatorMember.ID {
+		return errors.New("只能撤回自己发送的消息")
+	}
+	db.Find(&senderMember, "group_id = ? AND user_id = ?", group.ID, message.FromUserId)
+	senderMember.UserId = message.FromUserId // 已离开群组的发送者按普通成员处理
+
+	role := memberRole(group, operatorMember)
+	if role < constant.GROUP_ROLE_ADMIN || role <= memberRole(group, senderMember) {
+		return errors.New("没有权限撤回该消息")
+	}
+	return nil
+}
+
+// messageEvent 函数构造撤回或编辑通知，单聊发送给另一方，群聊发送给群组成员
+func messageEvent(db *gorm.DB, eventType string, operator model.User, message model.Message, content string, timestamp int64) (*protocol.Message, error) {
+	var to string
+	if message.MessageType == constant.MESSAGE_TYPE_GROUP {
+		var group model.Group
+		db.Unscoped().Select("uuid").First(&group, "id = ?", message.ToUserId)
+		to = group.Uuid
+	} else {
+		var toUser model.User
+		db.Select("uuid").First(&toUser, "id = ?", message.ToUserId)
+		to = toUser.Uuid
+	}
+	if to == "" {
+		return nil, errors.New("会话不存在")
+	}
+	return &protocol.Message{
+		Type:         eventType,
+		MessageType:  int32(message.MessageType),
+		From:         operator.Uuid,
+		FromUsername: operator.Username,
+		To:           to,
+		Id:           int64(message.ID),
+		Content:      content,
+		Timestamp:    timestamp,
+	}, nil
+}
@@ -1,20 +1,32 @@
 package service
 
 import (
+	"chat-room/config"              // 引入配置包，用于读取检索引擎配置
 	"chat-room/internal/dao/pool"   // 引入数据库连接池
 	"chat-room/internal/model"      // 引入数据模型包
+	"chat-room/internal/search"     // 引入消息全文检索包
 	"chat-room/pkg/common/constant" // 引入全局常量
 	"chat-room/pkg/common/request"  // 引入通用请求包
 	"chat-room/pkg/common/response" // 引入通用响应包
 	"chat-room/pkg/errors"          // 引入自定义错误处理包
 	"chat-room/pkg/global/log"      // 引入全局日志记录器
 	"chat-room/pkg/protocol"        // 引入消息协议包
-	"gorm.io/gorm"                  // 引入GORM ORM库
+	"sort"                          // 引入排序包，用于合并离线消息
+	"time"                          // 引入时间包，用于处理消息时间戳
+
+	"gorm.io/gorm"        // 引入GORM ORM库
+	"gorm.io/gorm/clause" // 引入GORM子句包，用于游标的插入或更新
 )
 
 // NULL_ID 定义了一个常量表示无效的ID
 const NULL_ID int32 = 0
 
+const (
+	DEFAULT_PAGE_SIZE    = 20  // 分页查询消息时默认的每页条数
+	MAX_PAGE_SIZE        = 100 // 分页查询消息时允许的最大每页条数
+	SEARCH_SNIPPET_WIDTH = 30  // 检索结果片段中关键词前后保留的字符数
+)
+
 // messageService 结构体实现消息服务的相关逻辑
 type messageService struct {
 }
@@ -22,8 +34,8 @@ type messageService struct {
 // MessageService 是全局的消息服务实例
 var MessageService = new(messageService)
 
-// GetMessages 函数根据请求参数获取消息列表
-func (m *messageService) GetMessages(message request.MessageRequest) ([]response.MessageResponse, error) {
+// GetMessages 函数根据请求参数分页获取userUuid与好友的单聊消息，或userUuid所在群组的群聊消息
+func (m *messageService) GetMessages(userUuid string, message request.MessageRequest) (response.MessagePageResponse, error) {
 	db := pool.GetDB() // 获取数据库连接实例
 
 	// 自动迁移消息表结构，确保表存在
@@ -33,65 +45,108 @@ func (m *messageService) GetMessages(message request.MessageRequest) ([]response
 	// 处理用户消息查询
 	if message.MessageType == constant.MESSAGE_TYPE_USER {
 		var queryUser *model.User
-		db.First(&queryUser, "uuid = ?", message.Uuid) // 根据UUID查询用户
+		db.First(&queryUser, "uuid = ?", userUuid) // 根据当前登录用户的UUID查询用户
 
 		if NULL_ID == queryUser.Id { // 如果用户不存在
-			return nil, errors.New("用户不存在")
+			return response.MessagePageResponse{}, errors.New("用户不存在")
 		}
 
 		var friend *model.User
 		db.First(&friend, "username = ?", message.FriendUsername) // 根据用户名查询好友信息
 		if NULL_ID == friend.Id {
-			return nil, errors.New("用户不存在")
+			return response.MessagePageResponse{}, errors.New("用户不存在")
 		}
 
-		var messages []response.MessageResponse
-
 		// 查询两个用户之间的消息
-		db.Raw("SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, u.username AS from_username, u.avatar, to_user.username AS to_username  FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id LEFT JOIN users AS to_user ON m.to_user_id = to_user.id WHERE from_user_id IN (?, ?) AND to_user_id IN (?, ?)",
-			queryUser.Id, friend.Id, queryUser.Id, friend.Id).Scan(&messages)
-
-		return messages, nil
+		query := "SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, m.edited_at, m.edited_at > 0 AS edited, m.recalled_at > 0 AS recalled, u.username AS from_username, u.avatar, to_user.username AS to_username  FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id LEFT JOIN users AS to_user ON m.to_user_id = to_user.id WHERE m.message_type = 1 AND ((m.from_user_id = ? AND m.to_user_id = ?) OR (m.from_user_id = ? AND m.to_user_id = ?))"
+		return pageMessages(db, query, []interface{}{queryUser.Id, friend.Id, friend.Id, queryUser.Id}, message), nil
 	}
 
 	// 处理群组消息查询
 	if message.MessageType == constant.MESSAGE_TYPE_GROUP {
-		messages, err := fetchGroupMessage(db, message.Uuid) // 调用辅助函数获取群组消息
+		messages, err := fetchGroupMessage(db, userUuid, message) // 调用辅助函数获取群组消息
 		if err != nil {
-			return nil, err
+			return response.MessagePageResponse{}, err
 		}
 
 		return messages, nil
 	}
 
-	return nil, errors.New("不支持查询类型") // 返回不支持的查询类型错误
+	return response.MessagePageResponse{}, errors.New("不支持查询类型") // 返回不支持的查询类型错误
 }
 
-// fetchGroupMessage 函数根据群组UUID获取群组消息
-func fetchGroupMessage(db *gorm.DB, toUuid string) ([]response.MessageResponse, error) {
+// fetchGroupMessage 函数根据群组UUID分页获取群组消息，只有群组成员可以查询
+func fetchGroupMessage(db *gorm.DB, userUuid string, message request.MessageRequest) (response.MessagePageResponse, error) {
 	var group model.Group
-	db.First(&group, "uuid = ?", toUuid) // 根据UUID查询群组
+	db.First(&group, "uuid = ?", message.Uuid) // 根据UUID查询群组
 	if group.ID <= 0 {
-		return nil, errors.New("群组不存在")
+		return response.MessagePageResponse{}, errors.New("群组不存在")
+	}
+	var queryUser model.User
+	db.First(&queryUser, "uuid = ?", userUuid) // 根据当前登录用户的UUID查询用户
+	if queryUser.Id <= 0 || !isGroupMember(db, group.ID, queryUser.Id) {
+		return response.MessagePageResponse{}, errors.New("不是群组成员")
+	}
+
+	// 查询群组内的消息
+	query := "SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, m.edited_at, m.edited_at > 0 AS edited, m.recalled_at > 0 AS recalled, u.username AS from_username, u.avatar FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id WHERE m.to_user_id = ? AND m.message_type = 2"
+	return pageMessages(db, query, []interface{}{group.ID}, message), nil
+}
+
+// pageMessages 函数在消息查询语句上追加游标条件和分页限制，返回按消息ID升序排列的一页消息
+// 指定afterId时从该消息之后向新消息方向翻页，否则从beforeId（未指定时为最新消息）向历史消息方向翻页
+func pageMessages(db *gorm.DB, query string, args []interface{}, message request.MessageRequest) response.MessagePageResponse {
+	limit := message.Limit
+	if limit <= 0 {
+		limit = DEFAULT_PAGE_SIZE
+	} else if limit > MAX_PAGE_SIZE {
+		limit = MAX_PAGE_SIZE
 	}
 
+	order := "DESC"
+	if message.AfterId > 0 {
+		query += " AND m.id > ?"
+		args = append(args, message.AfterId)
+		order = "ASC"
+	} else if message.BeforeId > 0 {
+		query += " AND m.id < ?"
+		args = append(args, message.BeforeId)
+	}
+	query += " ORDER BY m.id " + order + " LIMIT ?"
+	args = append(args, limit+1) // 多查询一条，用于判断是否还有更多消息
+
 	var messages []response.MessageResponse
+	db.Raw(query, args...).Scan(&messages)
 
-	// 查询群组内的消息
-	db.Raw("SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, u.username AS from_username, u.avatar FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id WHERE m.message_type = 2 AND m.to_user_id = ?",
-		group.ID).Scan(&messages)
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+	if order == "DESC" { // 向历史方向翻页时查询结果是倒序的，翻转为升序返回
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
 
-	return messages, nil
+	page := response.MessagePageResponse{Messages: messages, HasMore: hasMore}
+	if hasMore {
+		if order == "ASC" {
+			page.NextCursor = messages[len(messages)-1].ID
+		} else {
+			page.NextCursor = messages[0].ID
+		}
+	}
+	return page
 }
 
-// SaveMessage 函数保存消息记录到数据库
-func (m *messageService) SaveMessage(message protocol.Message) {
+// SaveMessage 函数保存消息记录到数据库，返回保存后的消息记录（包含服务端生成的ID和创建时间）
+func (m *messageService) SaveMessage(message protocol.Message) (*model.Message, error) {
 	db := pool.GetDB() // 获取数据库连接实例
 	var fromUser model.User
 	db.Find(&fromUser, "uuid = ?", message.From) // 根据消息发送者的UUID查询用户信息
 	if NULL_ID == fromUser.Id {
 		log.Logger.Error("SaveMessage not find from user", log.Any("SaveMessage not find from user", fromUser.Id))
-		return
+		return nil, errors.New("发送者不存在")
 	}
 
 	var toUserId int32 = 0
@@ -101,21 +156,27 @@ func (m *messageService) SaveMessage(message protocol.Message) {
 		var toUser model.User
 		db.Find(&toUser, "uuid = ?", message.To) // 根据消息接收者的UUID查询用户信息
 		if NULL_ID == toUser.Id {
-			return
+			return nil, errors.New("接收者不存在")
 		}
 		toUserId = toUser.Id
 	}
 
 	// 处理群组消息的保存
 	if message.MessageType == constant.MESSAGE_TYPE_GROUP {
-		var group model.Group
-		db.Find(&group, "uuid = ?", message.To) // 根据消息接收者的UUID查询群组信息
-		if NULL_ID == group.ID {
-			return
+		group, member, err := speakingMember(message.To, message.From) // 从缓存的发言状态获取群组，退出或被移出群组后不能再发送群消息
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSpeak(group, member); err != nil { // 被禁言的成员不能发送群消息
+			return nil, err
 		}
 		toUserId = group.ID
 	}
 
+	if NULL_ID == toUserId {
+		return nil, errors.New("不支持的消息类型")
+	}
+
 	// 创建并保存消息记录
 	saveMessage := model.Message{
 		FromUserId:  fromUser.Id,
@@ -125,5 +186,462 @@ func (m *messageService) SaveMessage(message protocol.Message) {
 		MessageType: int16(message.MessageType),
 		Url:         message.Url,
 	}
-	db.Save(&saveMessage) // 保存消息到数据库
+	if err := db.Save(&saveMessage).Error; err != nil { // 保存消息到数据库
+		log.Logger.Error("SaveMessage error", log.String("SaveMessage error", err.Error()))
+		return nil, errors.New("消息保存失败")
+	}
+
+	if saveMessage.ContentType == constant.TEXT { // 文本消息加入全文检索索引
+		messageSearchIndex.Index(search.Document{
+			MessageId:   saveMessage.ID,
+			MessageType: int32(saveMessage.MessageType),
+			FromUserId:  saveMessage.FromUserId,
+			ToUserId:    saveMessage.ToUserId,
+			Content:     saveMessage.Content,
+			CreatedAt:   saveMessage.CreatedAt,
+		})
+	}
+	return &saveMessage, nil
+}
+
+// ConversationKey 结构体用于标识一个会话：单聊为对方用户的uuid，群聊为群组的uuid
+type ConversationKey struct {
+	MessageType int32  // 会话类型，1.单聊 2.群聊
+	Uuid        string // 对方用户的uuid或群组的uuid
+}
+
+// offlineMessage 结构体用于接收离线消息查询结果
+type offlineMessage struct {
+	ID           int32
+	FromUuid     string
+	FromUsername string
+	Avatar       string
+	ToUuid       string
+	Content      string
+	ContentType  int32
+	MessageType  int32
+	Url          string
+	CreatedAt    time.Time
+}
+
+// GetOfflineMessages 函数获取用户的设备上次投递游标之后的所有单聊和群聊消息，按消息ID升序返回
+// 每个设备各自维护投递游标，一个设备收到的消息仍会补发给用户的其他设备
+// 返回的消息格式与在线转发时保持一致，群聊消息的From为群组uuid，To为发送者uuid
+func (m *messageService) GetOfflineMessages(userUuid, deviceId string) ([]protocol.Message, error) {
+	db := pool.GetDB()
+	pool.GetDB().AutoMigrate(&model.Message{}) // 自动迁移消息表结构，确保撤回字段存在
+	migrateCursors(db)
+
+	var user model.User
+	db.First(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	// 获取设备的全局起点，设备第一次连接时以当前最大消息ID为起点，不补发历史消息
+	// 同时确保用户的全局起点存在，起点之前的消息不计为未读
+	getCursorStart(db, user.Id, "")
+	start, created := getCursorStart(db, user.Id, deviceId)
+	if created {
+		return nil, nil
+	}
+
+	var userMessages []offlineMessage
+	// 发给该用户的单聊消息，以对方用户的会话游标为准，没有会话游标时以全局起点为准
+	db.Raw("SELECT m.id, u.uuid AS from_uuid, u.username AS from_username, u.avatar, ? AS to_uuid, m.content, m.content_type, m.message_type, m.url, m.created_at FROM messages AS m JOIN users AS u ON m.from_user_id = u.id LEFT JOIN message_cursors AS mc ON mc.user_id = ? AND mc.device_id = ? AND mc.message_type = 1 AND mc.conversation_id = m.from_user_id WHERE m.message_type = 1 AND m.to_user_id = ? AND m.recalled_at = 0 AND m.id > GREATEST(IFNULL(mc.last_message_id, 0), ?) ORDER BY m.id",
+		user.Uuid, user.Id, deviceId, user.Id, start).Scan(&userMessages)
+
+	var groupMessages []offlineMessage
+	// 用户所在群组中其他成员发送的群聊消息，From为群组uuid，与在线转发的格式一致
+	db.Raw("SELECT m.id, g.uuid AS from_uuid, u.username AS from_username, u.avatar, u.uuid AS to_uuid, m.content, m.content_type, m.message_type, m.url, m.created_at FROM messages AS m JOIN group_members AS gm ON gm.group_id = m.to_user_id AND gm.user_id = ? AND gm.deleted_at = 0 JOIN `groups` AS g ON g.id = m.to_user_id JOIN users AS u ON m.from_user_id = u.id LEFT JOIN message_cursors AS mc ON mc.user_id = ? AND mc.device_id = ? AND mc.message_type = 2 AND mc.conversation_id = m.to_user_id WHERE m.message_type = 2 AND m.from_user_id <> ? AND m.created_at >= gm.created_at AND m.recalled_at = 0 AND m.id > GREATEST(IFNULL(mc.last_message_id, 0), ?) ORDER BY m.id",
+		user.Id, user.Id, deviceId, user.Id, start).Scan(&groupMessages)
+
+	// 合并单聊和群聊消息，按消息ID升序排列
+	offlineMessages := append(userMessages, groupMessages...)
+	sort.Slice(offlineMessages, func(i, j int) bool {
+		return offlineMessages[i].ID < offlineMessages[j].ID
+	})
+
+	messages := make([]protocol.Message, 0, len(offlineMessages))
+	for _, offline := range offlineMessages {
+		messages = append(messages, protocol.Message{
+			Avatar:       offline.Avatar,
+			FromUsername: offline.FromUsername,
+			From:         offline.FromUuid,
+			To:           offline.ToUuid,
+			Content:      offline.Content,
+			ContentType:  offline.ContentType,
+			MessageType:  offline.MessageType,
+			Url:          offline.Url,
+			Id:           int64(offline.ID),
+			Timestamp:    offline.CreatedAt.UnixNano() / int64(time.Millisecond),
+		})
+	}
+	return messages, nil
+}
+
+// SaveCursors 函数保存用户的设备在各个会话的投递游标，游标只会前进不会后退
+func (m *messageService) SaveCursors(userUuid, deviceId string, cursors map[ConversationKey]int64) error {
+	if len(cursors) == 0 {
+		return nil
+	}
+	db := pool.GetDB()
+
+	var user model.User
+	db.First(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return errors.New("用户不存在")
+	}
+
+	for key, lastMessageId := range cursors {
+		conversationId := getConversationId(db, key.MessageType, key.Uuid)
+		if NULL_ID == conversationId {
+			continue
+		}
+
+		cursor := model.MessageCursor{
+			UserId:         user.Id,
+			DeviceId:       deviceId,
+			MessageType:    int16(key.MessageType),
+			ConversationId: conversationId,
+			LastMessageId:  int32(lastMessageId),
+		}
+		// 记录已存在时取较大的游标值，避免并发连接时游标回退
+		err := db.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"last_message_id": gorm.Expr("GREATEST(last_message_id, VALUES(last_message_id))"),
+				"updated_at":      time.Now(),
+			}),
+		}).Create(&cursor).Error
+		if err != nil {
+			log.Logger.Error("save message cursor error", log.String("save message cursor error", err.Error()))
+		}
+	}
+	return nil
+}
+
+// getCursorStart 函数获取设备的全局游标起点，deviceId为空时为用户计算未读数的起点，不存在时以当前最大消息ID创建，created表示本次新建
+// 起点之前的消息视为已投递、已读，避免启用游标前的历史消息被全部补发或计为未读
+func getCursorStart(db *gorm.DB, userId int32, deviceId string) (start int32, created bool) {
+	var cursor model.MessageCursor
+	db.First(&cursor, "user_id = ? AND device_id = ? AND message_type = 0 AND conversation_id = 0", userId, deviceId)
+	if NULL_ID != cursor.ID {
+		return cursor.LastMessageId, false
+	}
+
+	var maxId int32
+	db.Model(&model.Message{}).Select("IFNULL(MAX(id), 0)").Scan(&maxId)
+	cursor = model.MessageCursor{UserId: userId, DeviceId: deviceId, LastMessageId: maxId, LastReadId: maxId}
+	db.Create(&cursor)
+	return maxId, true
+}
+
+// migrateCursors 函数迁移游标表结构，投递游标改为按设备记录后删除旧的按会话唯一索引
+func migrateCursors(db *gorm.DB) {
+	db.AutoMigrate(&model.MessageCursor{})
+	if db.Migrator().HasIndex(&model.MessageCursor{}, "idx_cursor_conversation") {
+		db.Migrator().DropIndex(&model.MessageCursor{}, "idx_cursor_conversation")
+	}
+}
+
+// getConversationId 函数将会话的uuid转换为ID：单聊为对方用户ID，群聊为群组ID，不存在时返回NULL_ID
+func getConversationId(db *gorm.DB, messageType int32, conversationUuid string) int32 {
+	if messageType == constant.MESSAGE_TYPE_USER {
+		var friend model.User
+		db.Select("id").First(&friend, "uuid = ?", conversationUuid)
+		return friend.Id
+	}
+	if messageType == constant.MESSAGE_TYPE_GROUP {
+		var group model.Group
+		db.Select("id").First(&group, "uuid = ?", conversationUuid)
+		return group.ID
+	}
+	return NULL_ID
+}
+
+// MarkRead 函数保存用户在某个会话中的已读位置，已读位置只会前进不会后退
+// 已读位置保存在DeviceId为空的记录中，由用户的所有设备共享，不影响各设备的投递游标
+func (m *messageService) MarkRead(userUuid string, messageType int32, conversationUuid string, messageId int64) error {
+	if messageId <= 0 {
+		return errors.New("已读消息ID无效")
+	}
+	db := pool.GetDB()
+	migrateCursors(db) // 自动迁移游标表结构，确保表存在
+
+	var user model.User
+	db.First(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return errors.New("用户不存在")
+	}
+	if messageType == constant.MESSAGE_TYPE_GROUP && !GroupService.IsGroupMember(conversationUuid, userUuid) {
+		return errors.New("不是群组成员") // 不是群组成员时不能保存已读位置，也不能向群组广播已读回执
+	}
+	conversationId := getConversationId(db, messageType, conversationUuid)
+	if NULL_ID == conversationId {
+		return errors.New("会话不存在")
+	}
+
+	cursor := model.MessageCursor{
+		UserId:         user.Id,
+		MessageType:    int16(messageType),
+		ConversationId: conversationId,
+		LastReadId:     int32(messageId),
+	}
+	err := db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"last_read_id": gorm.Expr("GREATEST(last_read_id, VALUES(last_read_id))"),
+			"updated_at":   time.Now(),
+		}),
+	}).Create(&cursor).Error
+	if err != nil {
+		log.Logger.Error("mark read error", log.String("mark read error", err.Error()))
+		return errors.New("保存已读位置失败")
+	}
+	return nil
+}
+
+// conversationStat 结构体用于接收会话的最后一条消息ID和未读数的查询结果
+type conversationStat struct {
+	ConversationId int32
+	LastMessageId  int32
+	LastReadId     int32
+	Unread         int64
+}
+
+// GetConversations 函数获取用户的会话列表，包含每个会话的最后一条消息和未读数，按最后一条消息倒序排列
+// 单聊会话为与用户互发过消息的用户，群聊会话为用户所在的所有群组
+func (m *messageService) GetConversations(userUuid string) ([]response.ConversationResponse, error) {
+	db := pool.GetDB()
+	migrateCursors(db) // 自动迁移游标表结构，确保表存在
+
+	var user model.User
+	db.First(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+	start, _ := getCursorStart(db, user.Id, "")
+
+	// 单聊会话：对方用户ID、最后一条消息ID和已读位置
+	var userStats []conversationStat
+	db.Raw("SELECT t.peer_id AS conversation_id, MAX(t.id) AS last_message_id, IFNULL(MAX(mc.last_read_id), 0) AS last_read_id FROM (SELECT id, IF(from_user_id = ?, to_user_id, from_user_id) AS peer_id FROM messages WHERE message_type = 1 AND (from_user_id = ? OR to_user_id = ?)) AS t LEFT JOIN message_cursors AS mc ON mc.user_id = ? AND mc.device_id = '' AND mc.message_type = 1 AND mc.conversation_id = t.peer_id GROUP BY t.peer_id",
+		user.Id, user.Id, user.Id, user.Id).Scan(&userStats)
+	var userUnread []conversationStat
+	db.Raw("SELECT m.from_user_id AS conversation_id, COUNT(*) AS unread FROM messages AS m LEFT JOIN message_cursors AS mc ON mc.user_id = ? AND mc.device_id = '' AND mc.message_type = 1 AND mc.conversation_id = m.from_user_id WHERE m.message_type = 1 AND m.to_user_id = ? AND m.recalled_at = 0 AND m.id > GREATEST(IFNULL(mc.last_read_id, 0), ?) GROUP BY m.from_user_id",
+		user.Id, user.Id, start).Scan(&userUnread)
+
+	// 群聊会话：用户所在的群组、最后一条消息ID和已读位置
+	var groupStats []conversationStat
+	db.Raw("SELECT gm.group_id AS conversation_id, IFNULL(MAX(m.id), 0) AS last_message_id, IFNULL(MAX(mc.last_read_id), 0) AS last_read_id FROM group_members AS gm LEFT JOIN messages AS m ON m.message_type = 2 AND m.to_user_id = gm.group_id LEFT JOIN message_cursors AS mc ON mc.user_id = gm.user_id AND mc.device_id = '' AND mc.message_type = 2 AND mc.conversation_id = gm.group_id WHERE gm.user_id = ? AND gm.deleted_at = 0 GROUP BY gm.group_id",
+		user.Id).Scan(&groupStats)
+	var groupUnread []conversationStat
+	db.Raw("SELECT m.to_user_id AS conversation_id, COUNT(*) AS unread FROM group_members AS gm JOIN messages AS m ON m.message_type = 2 AND m.to_user_id = gm.group_id LEFT JOIN message_cursors AS mc ON mc.user_id = gm.user_id AND mc.device_id = '' AND mc.message_type = 2 AND mc.conversation_id = gm.group_id WHERE gm.user_id = ? AND gm.deleted_at = 0 AND m.from_user_id <> gm.user_id AND m.recalled_at = 0 AND m.id > GREATEST(IFNULL(mc.last_read_id, 0), ?) GROUP BY m.to_user_id",
+		user.Id, start).Scan(&groupUnread)
+
+	// 查询会话对端的用户、群组信息和每个会话的最后一条消息
+	peerIds, groupIds, lastMessageIds := []int32{0}, []int32{0}, []int32{0}
+	for _, stat := range userStats {
+		peerIds = append(peerIds, stat.ConversationId)
+		lastMessageIds = append(lastMessageIds, stat.LastMessageId)
+	}
+	for _, stat := range groupStats {
+		groupIds = append(groupIds, stat.ConversationId)
+		lastMessageIds = append(lastMessageIds, stat.LastMessageId)
+	}
+	var peers []model.User
+	db.Select("id", "uuid", "username", "nickname", "avatar").Where("id IN ?", peerIds).Find(&peers)
+	var groups []model.Group
+	db.Select("id", "uuid", "name").Where("id IN ?", groupIds).Find(&groups)
+	var lastMessages []response.MessageResponse
+	db.Raw("SELECT m.id, m.from_user_id, m.to_user_id, m.content, m.content_type, m.url, m.created_at, m.edited_at, m.edited_at > 0 AS edited, m.recalled_at > 0 AS recalled, u.username AS from_username, u.avatar FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id WHERE m.id IN ?",
+		lastMessageIds).Scan(&lastMessages)
+
+	peerMap := make(map[int32]model.User, len(peers))
+	for _, peer := range peers {
+		peerMap[peer.Id] = peer
+	}
+	groupMap := make(map[int32]model.Group, len(groups))
+	for _, group := range groups {
+		groupMap[group.ID] = group
+	}
+	lastMessageMap := make(map[int32]response.MessageResponse, len(lastMessages))
+	for _, lastMessage := range lastMessages {
+		lastMessageMap[lastMessage.ID] = lastMessage
+	}
+	unreadMap := make(map[ConversationKey]int64, len(userUnread)+len(groupUnread))
+	for _, stat := range userUnread {
+		unreadMap[ConversationKey{MessageType: constant.MESSAGE_TYPE_USER, Uuid: peerMap[stat.ConversationId].Uuid}] = stat.Unread
+	}
+	for _, stat := range groupUnread {
+		unreadMap[ConversationKey{MessageType: constant.MESSAGE_TYPE_GROUP, Uuid: groupMap[stat.ConversationId].Uuid}] = stat.Unread
+	}
+
+	conversations := make([]response.ConversationResponse, 0, len(userStats)+len(groupStats))
+	for _, stat := range userStats {
+		peer, ok := peerMap[stat.ConversationId]
+		if !ok {
+			continue
+		}
+		name := peer.Nickname
+		if name == "" {
+			name = peer.Username
+		}
+		conversations = append(conversations, newConversation(constant.MESSAGE_TYPE_USER, peer.Uuid, name, peer.Avatar, stat, lastMessageMap, unreadMap))
+	}
+	for _, stat := range groupStats {
+		group, ok := groupMap[stat.ConversationId]
+		if !ok {
+			continue
+		}
+		conversations = append(conversations, newConversation(constant.MESSAGE_TYPE_GROUP, group.Uuid, group.Name, "", stat, lastMessageMap, unreadMap))
+	}
+
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].LastMessageId > conversations[j].LastMessageId
+	})
+	return conversations, nil
+}
+
+// newConversation 函数组装一个会话的响应
+func newConversation(messageType int16, uuid, name, avatar string, stat conversationStat,
+	lastMessageMap map[int32]response.MessageResponse, unreadMap map[ConversationKey]int64) response.ConversationResponse {
+	conversation := response.ConversationResponse{
+		MessageType:   messageType,
+		Uuid:          uuid,
+		Name:          name,
+		Avatar:        avatar,
+		LastMessageId: stat.LastMessageId,
+		LastReadId:    stat.LastReadId,
+		UnreadCount:   unreadMap[ConversationKey{MessageType: int32(messageType), Uuid: uuid}],
+	}
+	if lastMessage, ok := lastMessageMap[stat.LastMessageId]; ok {
+		conversation.LastMessage = &lastMessage
+	}
+	return conversation
+}
+
+// messageSearchIndex 是消息全文检索索引，默认使用MySQL全文索引，启动时由InitSearchIndex按配置替换
+var messageSearchIndex = search.NewMySQLIndex(pool.GetDB())
+
+// InitSearchIndex 函数根据配置初始化消息检索索引，使用进程内索引时从数据库加载已有的文本消息
+func (m *messageService) InitSearchIndex() {
+	if config.GetConfig().Search.Engine != constant.SEARCH_MEMORY {
+		messageSearchIndex = search.NewMySQLIndex(pool.GetDB())
+		return
+	}
+
+	index := search.NewMemoryIndex()
+	var lastId int32 = 0
+	for {
+		// 按消息ID分批加载文本消息，避免一次性读取整张表
+		var documents []search.Document
+		pool.GetDB().Raw("SELECT id AS message_id, message_type, from_user_id, to_user_id, content, created_at FROM messages WHERE content_type = 1 AND id > ? ORDER BY id LIMIT 1000",
+			lastId).Scan(&documents)
+		if len(documents) == 0 {
+			break
+		}
+		for _, doc := range documents {
+			index.Index(doc)
+		}
+		lastId = documents[len(documents)-1].MessageId
+	}
+	messageSearchIndex = index
+	log.Logger.Info("init memory search index", log.Any("last message id", lastId))
+}
+
+// searchHit 结构体用于接收检索结果所在会话和发送者的查询结果
+type searchHit struct {
+	ID           int32
+	MessageType  int16
+	FromUserId   int32
+	CreatedAt    time.Time
+	FromUuid     string
+	FromUsername string
+	Avatar       string
+	ToUuid       string
+	ToUsername   string
+	GroupUuid    string
+	GroupName    string
+}
+
+// SearchMessages 函数在用户参与的单聊和群聊中检索消息，返回命中的会话、发送者、片段和高亮
+func (m *messageService) SearchMessages(userUuid string, searchRequest request.MessageSearchRequest) ([]response.MessageSearchResponse, error) {
+	if searchRequest.Keyword == "" {
+		return nil, errors.New("检索关键词不能为空")
+	}
+	db := pool.GetDB()
+
+	var user model.User
+	db.First(&user, "uuid = ?", userUuid)
+	if NULL_ID == user.Id {
+		return nil, errors.New("用户不存在")
+	}
+
+	var memberships []search.Membership
+	db.Model(&model.GroupMember{}).Select("group_id, created_at AS joined_at").Where("user_id = ?", user.Id).Scan(&memberships) // 用户所在的群组和加入时间，加入之前的群聊消息不能检索
+
+	limit := searchRequest.Limit
+	if limit <= 0 {
+		limit = DEFAULT_PAGE_SIZE
+	} else if limit > MAX_PAGE_SIZE {
+		limit = MAX_PAGE_SIZE
+	}
+
+	hits, err := messageSearchIndex.Search(search.Query{
+		Keyword: searchRequest.Keyword,
+		UserId:  user.Id,
+		Groups:  memberships,
+		Limit:   limit,
+	})
+	if err != nil {
+		log.Logger.Error("search messages error", log.String("search messages error", err.Error()))
+		return nil, errors.New("消息检索失败")
+	}
+	results := make([]response.MessageSearchResponse, 0, len(hits))
+	if len(hits) == 0 {
+		return results, nil
+	}
+
+	messageIds := make([]int32, 0, len(hits))
+	for _, hit := range hits {
+		messageIds = append(messageIds, hit.MessageId)
+	}
+
+	// 查询命中消息的发送者和所在会话
+	var searchHits []searchHit
+	db.Raw("SELECT m.id, m.message_type, m.from_user_id, m.created_at, u.uuid AS from_uuid, u.username AS from_username, u.avatar, to_user.uuid AS to_uuid, to_user.username AS to_username, g.uuid AS group_uuid, g.name AS group_name FROM messages AS m LEFT JOIN users AS u ON m.from_user_id = u.id LEFT JOIN users AS to_user ON m.message_type = 1 AND m.to_user_id = to_user.id LEFT JOIN `groups` AS g ON m.message_type = 2 AND m.to_user_id = g.id WHERE m.id IN ?",
+		messageIds).Scan(&searchHits)
+	hitMap := make(map[int32]searchHit, len(searchHits))
+	for _, hit := range searchHits {
+		hitMap[hit.ID] = hit
+	}
+
+	for _, hit := range hits {
+		detail, ok := hitMap[hit.MessageId]
+		if !ok {
+			continue
+		}
+		snippet, highlight := search.Highlight(hit.Content, searchRequest.Keyword, SEARCH_SNIPPET_WIDTH)
+		result := response.MessageSearchResponse{
+			MessageId:    hit.MessageId,
+			MessageType:  detail.MessageType,
+			FromUuid:     detail.FromUuid,
+			FromUsername: detail.FromUsername,
+			Avatar:       detail.Avatar,
+			Snippet:      snippet,
+			Highlight:    highlight,
+			CreatedAt:    detail.CreatedAt,
+		}
+		if detail.MessageType == constant.MESSAGE_TYPE_GROUP {
+			result.ConversationUuid = detail.GroupUuid
+			result.ConversationName = detail.GroupName
+		} else if detail.FromUserId == user.Id { // 自己发出的单聊消息，会话对端为接收者
+			result.ConversationUuid = detail.ToUuid
+			result.ConversationName = detail.ToUsername
+		} else {
+			result.ConversationUuid = detail.FromUuid
+			result.ConversationName = detail.FromUsername
+		}
+		results = append(results, result)
+	}
+	return results, nil
 }
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"chat-room/internal/dao/pool" // 引入数据库连接池
+	"chat-room/internal/model"    // 引入数据模型包
+
+	"gorm.io/gorm/clause" // 引入GORM子句包，用于路由记录的插入或更新
+)
+
+// nodeService 结构体实现用户到节点的路由表，Kafka模式下每个节点只接收其连接的用户相关的消息
+// 多节点部署时还用于统计用户在集群中的设备数和查看在线设备
+type nodeService struct {
+}
+
+// NodeService 是全局的节点路由服务实例
+var NodeService = new(nodeService)
+
+// Register 函数记录用户设备连接所在的节点、设备类型和远端地址，同一设备重连到其他节点时覆盖旧记录
+// 路由表在节点启动时由ClearNode迁移，每次连接不再检查表结构
+func (n *nodeService) Register(userUuid, deviceId, deviceType, remoteAddr, nodeId string) error {
+	return pool.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_uuid"}, {Name: "device_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"node_id", "device_type", "remote_addr", "created_at"}),
+	}).Create(&model.UserNode{UserUuid: userUuid, DeviceId: deviceId, DeviceType: deviceType, RemoteAddr: remoteAddr, NodeId: nodeId}).Error
+}
+
+// Unregister 函数删除用户设备在指定节点上的路由记录，设备已重连到其他节点时不会误删
+func (n *nodeService) Unregister(userUuid, deviceId, nodeId string) error {
+	return pool.GetDB().Where("user_uuid = ? AND device_id = ? AND node_id = ?", userUuid, deviceId, nodeId).
+		Delete(&model.UserNode{}).Error
+}
+
+// ClearNode 函数迁移路由表结构并删除节点的所有路由记录，节点启动时调用，清理上次异常退出时遗留的记录
+func (n *nodeService) ClearNode(nodeId string) error {
+	db := pool.GetDB()
+	if err := db.AutoMigrate(&model.UserNode{}); err != nil { // 自动迁移路由表结构，确保表存在
+		return err
+	}
+
+	return db.Where("node_id = ?", nodeId).Delete(&model.UserNode{}).Error
+}
+
+// CountDevices 函数统计用户在整个集群中在线的设备数，用于判断第一个设备上线和最后一个设备下线
+func (n *nodeService) CountDevices(userUuid string) (int64, error) {
+	var count int64
+	err := pool.GetDB().Model(&model.UserNode{}).Where("user_uuid = ?", userUuid).Count(&count).Error
+	return count, err
+}
+
+// GetSessions 函数获取用户在整个集群中所有在线设备的路由记录，用于查看在线设备
+func (n *nodeService) GetSessions(userUuid string) ([]model.UserNode, error) {
+	var sessions []model.UserNode
+	err := pool.GetDB().Where("user_uuid = ?", userUuid).Order("created_at").Find(&sessions).Error
+	return sessions, err
+}
+
+// GetNodes 函数获取一组用户的设备连接所在的节点id，去重后返回
+func (n *nodeService) GetNodes(userUuids []string) ([]string, error) {
+	var nodes []string
+	if len(userUuids) == 0 {
+		return nodes, nil
+	}
+	err := pool.GetDB().Model(&model.UserNode{}).Distinct("node_id").Where("user_uuid IN ?", userUuids).Pluck("node_id", &nodes).Error
+	return nodes, err
+}
+
+// GetAllNodes 函数获取所有有用户连接的节点id，用于广播消息
+func (n *nodeService) GetAllNodes() ([]string, error) {
+	var nodes []string
+	err := pool.GetDB().Model(&model.UserNode{}).Distinct("node_id").Pluck("node_id", &nodes).Error
+	return nodes, err
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"chat-room/config"              // 引入配置包，用于读取token签发配置
 	"chat-room/internal/dao/pool"   // 引入数据库连接池
 	"chat-room/internal/model"      // 引入数据模型包
-	"chat-room/pkg/common/request"  // 引入通用请求包
+	"chat-room/pkg/common/constant" // 引入全局常量
 	"chat-room/pkg/common/response" // 引入通用响应包
+	"chat-room/pkg/common/util"     // 引入工具包，用于签发和校验token
 	"chat-room/pkg/errors"          // 引入自定义错误处理包
 	"chat-room/pkg/global/log"      // 引入全局日志记录器
 	"time"                          // 引入时间包，用于处理时间相关操作
@@ -27,6 +29,12 @@ func (u *userService) Register(user *model.User) error {
 	if userCount > 0 {
 		return errors.New("user already exists") // 如果用户名已存在，返回错误
 	}
+	hashedPassword, err := util.HashPassword(user.Password) // 对密码进行哈希，数据库中不保存明文
+	if err != nil {
+		log.Logger.Error("hash password error", log.String("hash password error", err.Error()))
+		return errors.New("注册失败")
+	}
+	user.Password = hashedPassword
 	user.Uuid = uuid.New().String() // 生成新用户的UUID
 	user.CreateAt = time.Now()      // 设置用户创建时间
 	user.DeleteAt = 0               // 初始化删除时间为0
@@ -35,26 +43,102 @@ func (u *userService) Register(user *model.User) error {
 	return nil
 }
 
-// Login 函数用于用户登录验证
-func (u *userService) Login(user *model.User) bool {
-	pool.GetDB().AutoMigrate(&user) // 自动迁移用户表结构
-	log.Logger.Debug("user", log.Any("user in service", user))
+// Login 函数用于用户登录验证，验证通过后为设备签发access token和refresh token，deviceId为空时生成新的设备id
+func (u *userService) Login(user *model.User, deviceId string) (response.LoginResponse, error) {
+	pool.GetDB().AutoMigrate(&user)                                  // 自动迁移用户表结构
+	log.Logger.Debug("login", log.String("username", user.Username)) // 只记录用户名，不记录密码和密码哈希
 	db := pool.GetDB()
 
 	var queryUser *model.User
 	db.First(&queryUser, "username = ?", user.Username) // 根据用户名查询用户信息
-	log.Logger.Debug("queryUser", log.Any("queryUser", queryUser))
 
-	user.Uuid = queryUser.Uuid // 将查询到的用户UUID赋值给传入的user对象
+	if NULL_ID == queryUser.Id { // 用户不存在，同样比较一次哈希，避免通过响应时间判断用户名是否存在
+		util.CheckDummyPassword(user.Password)
+		return response.LoginResponse{}, errors.New("用户名或密码错误")
+	}
+	match, needRehash := util.CheckPassword(queryUser.Password, user.Password) // 校验密码
+	if !match {
+		return response.LoginResponse{}, errors.New("用户名或密码错误")
+	}
+	if needRehash { // 历史遗留的明文密码，登录成功后重新哈希保存
+		u.rehashPassword(queryUser, user.Password)
+	}
+
+	if deviceId == "" {
+		deviceId = uuid.New().String()
+	}
+	version, err := u.getDeviceVersion(queryUser.Uuid, deviceId) // 设备之前被远程下线过时，使用增加后的版本签发
+	if err != nil {
+		log.Logger.Error("get device token version error", log.String("get device token version error", err.Error()))
+		return response.LoginResponse{}, errors.New("签发token失败")
+	}
+	return u.generateToken(queryUser, deviceId, version)
+}
+
+// rehashPassword 函数将明文存储的密码哈希后写回数据库，失败时只记录日志，不影响本次登录
+func (u *userService) rehashPassword(user *model.User, password string) {
+	hashedPassword, err := util.HashPassword(password)
+	if err != nil {
+		log.Logger.Error("rehash password error", log.String("rehash password error", err.Error()))
+		return
+	}
+	pool.GetDB().Model(user).Update("password", hashedPassword) // 更新用户的密码为哈希值
+}
+
+// RefreshToken 函数校验refresh token，并为对应用户的同一设备重新签发一组token，设备被远程下线后refresh token失效
+func (u *userService) RefreshToken(refreshToken string) (response.LoginResponse, error) {
+	claims, err := util.ParseToken(refreshToken, constant.REFRESH_TOKEN, config.GetConfig().Auth.Secret)
+	if err != nil {
+		return response.LoginResponse{}, err
+	}
+	if !u.CheckDeviceToken(claims.Uuid, claims.DeviceId, claims.Version) {
+		return response.LoginResponse{}, errors.New("设备已下线，请重新登录")
+	}
+
+	var queryUser *model.User
+	pool.GetDB().First(&queryUser, "uuid = ?", claims.Uuid) // 根据token中的uuid查询用户信息
+	if NULL_ID == queryUser.Id {
+		return response.LoginResponse{}, errors.New("用户不存在")
+	}
+
+	return u.generateToken(queryUser, claims.DeviceId, claims.Version)
+}
+
+// generateToken 函数为用户的设备签发access token和refresh token，并封装登录响应
+func (u *userService) generateToken(user *model.User, deviceId string, version int32) (response.LoginResponse, error) {
+	authConfig := config.GetConfig().Auth
+	accessToken, accessExpiresAt, err := util.GenerateToken(user.Uuid, deviceId, version, constant.ACCESS_TOKEN, authConfig.Secret,
+		time.Duration(authConfig.AccessTokenExpire)*time.Minute)
+	if err != nil {
+		log.Logger.Error("generate access token error", log.String("generate access token error", err.Error()))
+		return response.LoginResponse{}, errors.New("签发token失败")
+	}
+
+	refreshToken, refreshExpiresAt, err := util.GenerateToken(user.Uuid, deviceId, version, constant.REFRESH_TOKEN, authConfig.Secret,
+		time.Duration(authConfig.RefreshTokenExpire)*time.Minute)
+	if err != nil {
+		log.Logger.Error("generate refresh token error", log.String("generate refresh token error", err.Error()))
+		return response.LoginResponse{}, errors.New("签发token失败")
+	}
 
-	return queryUser.Password == user.Password // 返回密码匹配结果
+	return response.LoginResponse{
+		Uuid:             user.Uuid,
+		Username:         user.Username,
+		Nickname:         user.Nickname,
+		Avatar:           user.Avatar,
+		DeviceId:         deviceId,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  accessExpiresAt.Unix(),
+		RefreshExpiresAt: refreshExpiresAt.Unix(),
+	}, nil
 }
 
-// ModifyUserInfo 函数用于修改用户信息
-func (u *userService) ModifyUserInfo(user *model.User) error {
+// ModifyUserInfo 函数用于修改userUuid对应用户的信息，user中的用户名和uuid不作为修改对象的依据
+func (u *userService) ModifyUserInfo(userUuid string, user *model.User) error {
 	var queryUser *model.User
 	db := pool.GetDB()
-	db.First(&queryUser, "username = ?", user.Username) // 根据用户名查询用户信息
+	db.First(&queryUser, "uuid = ?", userUuid) // 根据当前登录用户的UUID查询用户信息
 	log.Logger.Debug("queryUser", log.Any("queryUser", queryUser))
 	var nullId int32 = 0
 	if nullId == queryUser.Id { // 如果用户不存在，返回错误
@@ -63,26 +147,37 @@ func (u *userService) ModifyUserInfo(user *model.User) error {
 	// 更新用户信息
 	queryUser.Nickname = user.Nickname
 	queryUser.Email = user.Email
-	queryUser.Password = user.Password
+	if user.Password != "" { // 只有传入新密码时才修改密码，并哈希后保存
+		hashedPassword, err := util.HashPassword(user.Password)
+		if err != nil {
+			log.Logger.Error("hash password error", log.String("hash password error", err.Error()))
+			return errors.New("修改密码失败")
+		}
+		queryUser.Password = hashedPassword
+	}
 
 	db.Save(queryUser) // 保存更新后的用户信息
+	invalidateUserProfile(queryUser.Uuid)
 	return nil
 }
 
-// GetUserDetails 函数根据用户UUID获取用户详细信息
+// GetUserDetails 函数根据用户UUID获取用户详细信息，只包含uuid、用户名、昵称和头像，优先从缓存读取
 func (u *userService) GetUserDetails(uuid string) model.User {
-	var queryUser *model.User
-	db := pool.GetDB()
-	// 查询并返回用户的基本信息
-	db.Select("uuid", "username", "nickname", "avatar").First(&queryUser, "uuid = ?", uuid)
-	return *queryUser
+	users := getUserProfiles([]string{uuid})
+	if len(users) == 0 {
+		return model.User{}
+	}
+	return users[0]
 }
 
-// GetUserOrGroupByName 函数通过名称查找群组或者用户
-func (u *userService) GetUserOrGroupByName(name string) response.SearchResponse {
+// GetUserOrGroupByName 函数通过名称查找群组或者用户，屏蔽了userUuid的用户不会出现在结果中
+func (u *userService) GetUserOrGroupByName(userUuid, name string) response.SearchResponse {
 	var queryUser *model.User
 	db := pool.GetDB()
 	db.Select("uuid", "username", "nickname", "avatar").First(&queryUser, "username = ?", name) // 查询用户信息
+	if queryUser.Uuid != "" && u.IsBlocked(queryUser.Uuid, userUuid) {
+		queryUser = &model.User{} // 对被屏蔽的用户隐藏屏蔽者
+	}
 
 	var queryGroup *model.Group
 	db.Select("uuid", "name").First(&queryGroup, "name = ?", name) // 查询群组信息
@@ -108,45 +203,41 @@ func (u *userService) GetUserList(uuid string) []model.User {
 
 	var queryUsers []model.User
 	// 查询用户的好友列表
-	db.Raw("SELECT u.username, u.uuid, u.avatar FROM user_friends AS uf JOIN users AS u ON uf.friend_id = u.id WHERE uf.user_id = ?", queryUser.Id).Scan(&queryUsers)
+	db.Raw("SELECT u.username, u.uuid, u.avatar FROM user_friends AS uf JOIN users AS u ON uf.friend_id = u.id WHERE uf.user_id = ? AND uf.deleted_at = 0", queryUser.Id).Scan(&queryUsers)
 
 	return queryUsers
 }
 
-// AddFriend 函数用于添加好友
-func (u *userService) AddFriend(userFriendRequest *request.FriendRequest) error {
-	var queryUser *model.User
+// GetFriendUuids 函数获取与用户存在好友关系（任一方向）的所有用户uuid
+func (u *userService) GetFriendUuids(uuid string) []string {
 	db := pool.GetDB()
-	db.First(&queryUser, "uuid = ?", userFriendRequest.Uuid) // 根据UUID查询用户信息
-	log.Logger.Debug("queryUser", log.Any("queryUser", queryUser))
-	var nullId int32 = 0
-	if nullId == queryUser.Id { // 如果用户不存在，返回错误
-		return errors.New("用户不存在")
-	}
 
-	var friend *model.User
-	db.First(&friend, "username = ?", userFriendRequest.FriendUsername) // 根据用户名查询好友信息
-	if nullId == friend.Id {                                            // 如果好友不存在，返回错误
-		return errors.New("已添加该好友")
+	var queryUser *model.User
+	db.First(&queryUser, "uuid = ?", uuid) // 根据UUID查询用户信息
+	if NULL_ID == queryUser.Id {
+		return nil
 	}
 
-	// 创建好友关系
-	userFriend := model.UserFriend{
-		UserId:   queryUser.Id,
-		FriendId: friend.Id,
-	}
+	var friendUuids []string
+	db.Raw("SELECT DISTINCT u.uuid FROM user_friends AS uf JOIN users AS u ON u.id = IF(uf.user_id = ?, uf.friend_id, uf.user_id) WHERE (uf.user_id = ? OR uf.friend_id = ?) AND uf.deleted_at = 0",
+		queryUser.Id, queryUser.Id, queryUser.Id).Scan(&friendUuids)
+	return friendUuids
+}
 
-	var userFriendQuery *model.UserFriend
-	db.First(&userFriendQuery, "user_id = ? and friend_id = ?", queryUser.Id, friend.Id) // 检查好友关系是否已存在
-	if userFriendQuery.ID != nullId {
-		return errors.New("该用户已经是你好友")
+// CanViewPresence 函数判断viewerUuid能否查看userUuid的在线状态，只有本人和未被对方屏蔽的好友可以查看
+func (u *userService) CanViewPresence(viewerUuid, userUuid string) bool {
+	if viewerUuid == userUuid {
+		return true
 	}
-
-	db.AutoMigrate(&userFriend) // 自动迁移好友表结构
-	db.Save(&userFriend)        // 保存好友关系
-	log.Logger.Debug("userFriend", log.Any("userFriend", userFriend))
-
-	return nil
+	if u.IsBlocked(userUuid, viewerUuid) {
+		return false
+	}
+	for _, friendUuid := range u.GetFriendUuids(userUuid) {
+		if friendUuid == viewerUuid {
+			return true
+		}
+	}
+	return false
 }
 
 // ModifyUserAvatar 函数用于修改用户头像
@@ -160,5 +251,6 @@ func (u *userService) ModifyUserAvatar(avatar string, userUuid string) error {
 	}
 
 	db.Model(&queryUser).Update("avatar", avatar) // 更新用户的头像信息
+	invalidateUserProfile(userUuid)
 	return nil
 }
@@ -5,11 +5,84 @@ package constant
 const (
 	HEAT_BEAT = "heatbeat" // 心跳消息，用于维持长连接的存活状态
 	PONG      = "pong"     // Pong消息，通常用于回应心跳包
+	ERROR     = "error"    // 错误消息，服务端拒绝客户端消息时返回，Content为错误原因
+	ACK       = "ack"      // 消息确认，消息持久化成功后回复给发送者
+	NACK      = "nack"     // 消息否认，消息持久化失败后回复给发送者，Content为失败原因
+	READ      = "read"     // 已读回执，Id为已读的最后一条消息ID，转发给会话的另一方或群组其他成员
+	TYPING    = "typing"   // 正在输入，不持久化，转发给会话的另一方或群组其他成员
+	PRESENCE  = "presence" // 在线状态，Content为状态，Timestamp为最后在线时间，转发给好友
+	SYSTEM    = "system"   // 群组系统消息，Content为事件类型，From为操作者，To为群组uuid，Target为涉及的成员
+	FRIEND    = "friend"   // 好友通知，Content为事件类型，From为操作者，To为另一方，Id为好友请求id
+	RECALL    = "recall"   // 消息撤回通知，Id为被撤回的消息ID，From为操作者，To为单聊的另一方或群组uuid
+	EDIT      = "edit"     // 消息编辑通知，Id为被编辑的消息ID，Content为编辑后的内容，From为发送者，To为单聊的另一方或群组uuid
+	LOGOUT    = "logout"   // 远程下线通知，只在节点之间传递，From和To为用户uuid，Content为被下线的设备id
+
+	// 在线状态常量
+	PRESENCE_ONLINE  = "online"  // 在线
+	PRESENCE_AWAY    = "away"    // 离开
+	PRESENCE_OFFLINE = "offline" // 离线
+
+	// 慢消费者策略常量，发送队列写满时使用
+	SLOW_CONSUMER_DROP_OLDEST = "drop_oldest" // 丢弃队列中最旧的消息
+	SLOW_CONSUMER_DISCONNECT  = "disconnect"  // 断开连接，未写入的消息在重连后补发
+	SLOW_CONSUMER_OFFLINE     = "offline"     // 后续消息转为离线补发，队列清空后从数据库补发
+
+	// 设备类型常量，同一用户可以在多个设备上同时登录
+	DEVICE_WEB     = "web"     // 浏览器
+	DEVICE_DESKTOP = "desktop" // 桌面客户端
+	DEVICE_MOBILE  = "mobile"  // 手机客户端
+
+	// 好友通知的事件类型
+	FRIEND_EVENT_REQUEST = "request" // 收到好友请求
+	FRIEND_EVENT_ACCEPT  = "accept"  // 好友请求被通过，双方成为好友
+	FRIEND_EVENT_REJECT  = "reject"  // 好友请求被拒绝
+	FRIEND_EVENT_CANCEL  = "cancel"  // 好友请求被发送者取消
+	FRIEND_EVENT_DELETE  = "delete"  // 好友关系被删除，Id为0
+
+	// 好友请求的状态
+	FRIEND_REQUEST_PENDING  = 0 // 待处理
+	FRIEND_REQUEST_ACCEPTED = 1 // 已通过
+	FRIEND_REQUEST_REJECTED = 2 // 已拒绝
+	FRIEND_REQUEST_CANCELED = 3 // 已取消
+
+	// 群聊消息的帧格式，客户端建立连接时通过groupFrame查询参数选择
+	GROUP_FRAME_FORWARD = "forward" // 默认格式，From为群组uuid、To为发送者uuid
+	GROUP_FRAME_COMPACT = "compact" // 原样转发发送者的消息，From为发送者uuid、To为群组uuid，接收者由客户端推断为当前用户
 
 	// 消息类型常量，用于区分消息是单聊还是群聊
 	MESSAGE_TYPE_USER  = 1 // 单聊消息
 	MESSAGE_TYPE_GROUP = 2 // 群聊消息
 
+	// 群成员角色常量
+	GROUP_ROLE_MEMBER = 0 // 普通成员
+	GROUP_ROLE_ADMIN  = 1 // 管理员
+	GROUP_ROLE_OWNER  = 2 // 群主
+
+	// 群组系统消息的事件类型
+	GROUP_EVENT_JOIN       = "join"      // 成员加入群组
+	GROUP_EVENT_LEAVE      = "leave"     // 成员退出群组
+	GROUP_EVENT_KICK       = "kick"      // 成员被移出群组
+	GROUP_EVENT_PROMOTE    = "promote"   // 成员被设为管理员
+	GROUP_EVENT_DEMOTE     = "demote"    // 管理员被取消
+	GROUP_EVENT_TRANSFER   = "transfer"  // 群主转让给Target
+	GROUP_EVENT_DISSOLVE   = "dissolve"  // 群组被解散
+	GROUP_EVENT_MUTE       = "mute"      // 成员被禁言
+	GROUP_EVENT_UNMUTE     = "unmute"    // 成员被解除禁言
+	GROUP_EVENT_MUTE_ALL   = "muteAll"   // 群组开启全员禁言
+	GROUP_EVENT_UNMUTE_ALL = "unmuteAll" // 群组关闭全员禁言
+	GROUP_EVENT_REQUEST    = "request"   // 用户申请加入群组，只发送给群主、管理员和申请人，Id为申请id
+	GROUP_EVENT_REJECT     = "reject"    // 加群申请被拒绝，只发送给群主、管理员和申请人，Id为申请id
+
+	// 群组的加群方式
+	GROUP_JOIN_OPEN     = 0 // 直接加入
+	GROUP_JOIN_APPROVAL = 1 // 需要群主或管理员审核
+	GROUP_JOIN_INVITE   = 2 // 只能通过邀请链接加入
+
+	// 加群申请的状态
+	GROUP_REQUEST_PENDING  = 0 // 待审核
+	GROUP_REQUEST_APPROVED = 1 // 已通过
+	GROUP_REQUEST_REJECTED = 2 // 已拒绝
+
 	// 消息内容类型常量，用于区分消息的内容类型
 	TEXT         = 1 // 文字消息
 	FILE         = 2 // 文件消息
@@ -22,4 +95,23 @@ const (
 	// 消息队列类型常量，用于区分使用的消息队列
 	GO_CHANNEL = "gochannel" // 使用Go内置的channel作为消息队列
 	KAFKA      = "kafka"     // 使用Kafka作为消息队列
+	REDIS      = "redis"     // 使用Redis发布订阅作为消息队列
+
+	// 消息检索引擎类型常量
+	SEARCH_MYSQL  = "mysql"  // 使用MySQL的FULLTEXT索引
+	SEARCH_MEMORY = "memory" // 使用进程内的倒排索引
+
+	// 群成员和用户资料缓存类型常量
+	CACHE_MEMORY = "memory" // 使用进程内的LRU缓存
+	CACHE_REDIS  = "redis"  // 使用Redis缓存
+)
+
+const (
+	// token类型常量，用于区分access token和refresh token
+	ACCESS_TOKEN  = "access"  // 访问接口和建立WebSocket连接使用的token
+	REFRESH_TOKEN = "refresh" // 用于换取新的access token
+
+	// gin上下文中保存认证信息的键名
+	CONTEXT_USER_UUID = "userUuid" // 认证通过后当前用户的uuid
+	CONTEXT_DEVICE_ID = "deviceId" // 认证通过后token绑定的设备id
 )
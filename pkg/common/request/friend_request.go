@@ -4,4 +4,5 @@ package request
 type FriendRequest struct {
 	Uuid           string // 发送请求的用户UUID
 	FriendUsername string // 要添加的好友用户名
+	Reason         string // 验证消息
 }
@@ -0,0 +1,22 @@
+package request
+
+// GroupMuteRequest 结构体用于封装禁言群成员的请求参数
+type GroupMuteRequest struct {
+	Duration int64 `json:"duration"` // 禁言时长，单位为秒，0表示永久禁言
+}
+
+// JoinGroupRequest 结构体用于封装申请加入群组的请求参数，群组需要审核时作为申请理由
+type JoinGroupRequest struct {
+	Reason string `json:"reason"` // 申请理由
+}
+
+// GroupInviteRequest 结构体用于封装创建群组邀请链接的请求参数
+type GroupInviteRequest struct {
+	Expire  int64 `json:"expire"`  // 有效时长，单位为秒，0表示永不过期
+	MaxUses int32 `json:"maxUses"` // 最多可以使用的次数，0表示不限次数
+}
+
+// GroupPolicyRequest 结构体用于封装修改群组加群方式的请求参数
+type GroupPolicyRequest struct {
+	JoinPolicy int16 `json:"joinPolicy"` // 加群方式，0表示直接加入，1表示需要审核，2表示只能通过邀请链接加入
+}
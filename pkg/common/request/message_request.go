@@ -2,7 +2,21 @@ package request
 
 // MessageRequest 结构体用于封装获取消息的请求参数
 type MessageRequest struct {
-	MessageType    int32  `json:"messageType"`    // 消息类型（单聊或群聊）
-	Uuid           string `json:"uuid"`           // 当前用户的UUID
-	FriendUsername string `json:"friendUsername"` // 好友的用户名（用于单聊时指定好友）
+	MessageType    int32  `json:"messageType"`              // 消息类型（单聊或群聊）
+	Uuid           string `json:"uuid"`                     // 群聊时为群组的UUID，单聊时忽略，以当前登录用户为准
+	FriendUsername string `json:"friendUsername"`           // 好友的用户名（用于单聊时指定好友）
+	BeforeId       int32  `json:"beforeId" form:"beforeId"` // 只返回ID小于该值的消息，用于向前翻阅历史消息
+	AfterId        int32  `json:"afterId" form:"afterId"`   // 只返回ID大于该值的消息，用于拉取新消息
+	Limit          int    `json:"limit" form:"limit"`       // 每页返回的消息条数，默认20，最大100
+}
+
+// MessageSearchRequest 结构体用于封装检索消息的请求参数
+type MessageSearchRequest struct {
+	Keyword string `json:"keyword" form:"keyword"` // 检索关键词
+	Limit   int    `json:"limit" form:"limit"`     // 最多返回的结果数，默认20，最大100
+}
+
+// EditMessageRequest 结构体用于封装编辑消息的请求参数
+type EditMessageRequest struct {
+	Content string `json:"content"` // 编辑后的消息内容
 }
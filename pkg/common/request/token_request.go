@@ -0,0 +1,6 @@
+package request
+
+// RefreshTokenRequest 结构体用于封装刷新token的请求参数
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"` // 登录时签发的refresh token
+}
@@ -0,0 +1,16 @@
+package response
+
+import "time"
+
+// FriendRequestResponse 结构体用于封装好友请求的响应
+type FriendRequestResponse struct {
+	Id        int32     `json:"id"`       // 好友请求的ID
+	Uuid      string    `json:"uuid"`     // 对方的UUID：收到的请求为发送者，发出的请求为接收者
+	Username  string    `json:"username"` // 对方的用户名
+	Nickname  string    `json:"nickname"` // 对方的昵称
+	Avatar    string    `json:"avatar"`   // 对方的头像
+	Reason    string    `json:"reason"`   // 验证消息
+	Status    int16     `json:"status"`   // 请求状态，0表示待处理，1表示已通过，2表示已拒绝，3表示已取消
+	Incoming  bool      `json:"incoming"` // 是否为当前用户收到的请求
+	CreatedAt time.Time `json:"createAt"` // 发送请求的时间
+}
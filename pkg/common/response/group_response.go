@@ -4,9 +4,45 @@ import "time"
 
 // GroupResponse 结构体用于封装群组信息的响应
 type GroupResponse struct {
-	Uuid      string    `json:"uuid"`     // 群组的UUID
-	GroupId   int32     `json:"groupId"`  // 群组的ID
-	CreatedAt time.Time `json:"createAt"` // 群组的创建时间
-	Name      string    `json:"name"`     // 群组的名称
-	Notice    string    `json:"notice"`   // 群组的公告
+	Uuid       string    `json:"uuid"`       // 群组的UUID
+	GroupId    int32     `json:"groupId"`    // 群组的ID
+	CreatedAt  time.Time `json:"createAt"`   // 群组的创建时间
+	Name       string    `json:"name"`       // 群组的名称
+	Notice     string    `json:"notice"`     // 群组的公告
+	MuteAll    int16     `json:"muteAll"`    // 是否开启全员禁言，0表示关闭，1表示开启
+	JoinPolicy int16     `json:"joinPolicy"` // 加群方式，0表示直接加入，1表示需要审核，2表示只能通过邀请链接加入
+}
+
+// GroupMemberResponse 结构体用于封装群成员信息的响应
+type GroupMemberResponse struct {
+	Uuid      string `json:"uuid"`      // 成员的UUID
+	Username  string `json:"username"`  // 成员的用户名
+	Nickname  string `json:"nickname"`  // 成员的昵称
+	Avatar    string `json:"avatar"`    // 成员的头像
+	Role      int16  `json:"role"`      // 成员的角色，0表示普通成员，1表示管理员，2表示群主
+	Mute      int16  `json:"mute"`      // 成员是否处于禁言中，限时禁言到期后为0
+	MuteUntil int64  `json:"muteUntil"` // 禁言截止的毫秒时间戳，0表示永久禁言或未禁言
+}
+
+// GroupInviteResponse 结构体用于封装群组邀请链接的响应
+type GroupInviteResponse struct {
+	Code      string    `json:"code"`      // 邀请码
+	GroupUuid string    `json:"groupUuid"` // 邀请加入的群组UUID
+	CreatedAt time.Time `json:"createAt"`  // 邀请链接的创建时间
+	ExpiresAt int64     `json:"expiresAt"` // 过期的毫秒时间戳，0表示永不过期
+	MaxUses   int32     `json:"maxUses"`   // 最多可以使用的次数，0表示不限次数
+	Uses      int32     `json:"uses"`      // 已使用的次数
+}
+
+// GroupJoinRequestResponse 结构体用于封装加群申请的响应
+type GroupJoinRequestResponse struct {
+	Id        int32     `json:"id"`        // 申请的ID
+	GroupUuid string    `json:"groupUuid"` // 申请加入的群组UUID
+	Uuid      string    `json:"uuid"`      // 申请人的UUID
+	Username  string    `json:"username"`  // 申请人的用户名
+	Nickname  string    `json:"nickname"`  // 申请人的昵称
+	Avatar    string    `json:"avatar"`    // 申请人的头像
+	Reason    string    `json:"reason"`    // 申请理由
+	Status    int16     `json:"status"`    // 申请状态，0表示待审核，1表示已通过，2表示已拒绝
+	CreatedAt time.Time `json:"createAt"`  // 提交申请的时间
 }
@@ -0,0 +1,14 @@
+package response
+
+// LoginResponse 结构体用于封装登录或刷新token后的响应
+type LoginResponse struct {
+	Uuid             string `json:"uuid"`             // 用户的UUID
+	Username         string `json:"username"`         // 用户名
+	Nickname         string `json:"nickname"`         // 昵称
+	Avatar           string `json:"avatar"`           // 头像
+	DeviceId         string `json:"deviceId"`         // token绑定的设备id，登录时未指定则由服务端生成，客户端应持久保存
+	AccessToken      string `json:"accessToken"`      // 访问接口使用的token
+	RefreshToken     string `json:"refreshToken"`     // 用于换取新access token的token
+	AccessExpiresAt  int64  `json:"accessExpiresAt"`  // access token过期时间（Unix时间戳，秒）
+	RefreshExpiresAt int64  `json:"refreshExpiresAt"` // refresh token过期时间（Unix时间戳，秒）
+}
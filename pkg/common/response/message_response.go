@@ -14,4 +14,32 @@ type MessageResponse struct {
 	ToUsername   string    `json:"toUsername"`                                      // 接收消息的用户名（用于单聊）
 	Avatar       string    `json:"avatar"`                                          // 发送消息用户的头像
 	Url          string    `json:"url"`                                             // 消息中包含的URL（用于文件或多媒体消息）
+	Edited       bool      `json:"edited"`                                          // 消息是否被编辑过
+	EditedAt     int64     `json:"editedAt"`                                        // 最后一次编辑的毫秒时间戳，未编辑过时为0
+	Recalled     bool      `json:"recalled"`                                        // 消息是否已被撤回，撤回后内容和URL为空
+}
+
+// MessageEditResponse 结构体用于封装消息的一条编辑历史
+type MessageEditResponse struct {
+	Content   string    `json:"content"`  // 本次编辑前的内容
+	CreatedAt time.Time `json:"createAt"` // 编辑的时间
+}
+
+// MessagePageResponse 结构体用于封装分页查询消息的响应
+type MessagePageResponse struct {
+	Messages   []MessageResponse `json:"messages"`   // 本页消息，按消息ID升序排列
+	HasMore    bool              `json:"hasMore"`    // 翻页方向上是否还有更多消息
+	NextCursor int32             `json:"nextCursor"` // 下一页的游标：向前翻页时作为beforeId，拉取新消息时作为afterId，没有更多消息时为0
+}
+
+// ConversationResponse 结构体用于封装会话列表中的一个会话
+type ConversationResponse struct {
+	MessageType   int16            `json:"messageType"`   // 会话类型，1.单聊 2.群聊
+	Uuid          string           `json:"uuid"`          // 会话标识：单聊为对方用户的uuid，群聊为群组的uuid
+	Name          string           `json:"name"`          // 会话名称：单聊为对方昵称或用户名，群聊为群名称
+	Avatar        string           `json:"avatar"`        // 单聊为对方用户的头像
+	LastMessageId int32            `json:"lastMessageId"` // 会话中最后一条消息的ID，没有消息时为0
+	LastMessage   *MessageResponse `json:"lastMessage"`   // 会话中的最后一条消息，没有消息时为null
+	LastReadId    int32            `json:"lastReadId"`    // 用户在该会话中已读的最后一条消息ID
+	UnreadCount   int64            `json:"unreadCount"`   // 未读消息数
 }
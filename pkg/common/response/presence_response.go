@@ -0,0 +1,8 @@
+package response
+
+// PresenceResponse 结构体用于封装用户在线状态的响应
+type PresenceResponse struct {
+	Uuid     string `json:"uuid"`     // 用户的UUID
+	Status   string `json:"status"`   // 在线状态：online、away或offline
+	LastSeen int64  `json:"lastSeen"` // 最后在线时间（Unix时间戳，毫秒），从未上线时为0
+}
@@ -1,9 +1,26 @@
 package response
 
-import "chat-room/internal/model"
+import (
+	"chat-room/internal/model"
+	"time"
+)
 
 // SearchResponse 结构体用于封装用户或群组的查询响应
 type SearchResponse struct {
 	User  model.User  `json:"user"`  // 查询到的用户信息
 	Group model.Group `json:"group"` // 查询到的群组信息
 }
+
+// MessageSearchResponse 结构体用于封装一条消息检索结果
+type MessageSearchResponse struct {
+	MessageId        int32     `json:"messageId"`        // 命中的消息ID
+	MessageType      int16     `json:"messageType"`      // 消息类型，1.单聊 2.群聊
+	ConversationUuid string    `json:"conversationUuid"` // 会话标识：单聊为对方用户的uuid，群聊为群组的uuid
+	ConversationName string    `json:"conversationName"` // 会话名称：单聊为对方用户名，群聊为群名称
+	FromUuid         string    `json:"fromUuid"`         // 发送者的uuid
+	FromUsername     string    `json:"fromUsername"`     // 发送者的用户名
+	Avatar           string    `json:"avatar"`           // 发送者的头像
+	Snippet          string    `json:"snippet"`          // 关键词附近的内容片段
+	Highlight        string    `json:"highlight"`        // 用<em>标签标出关键词的内容片段，已做HTML转义
+	CreatedAt        time.Time `json:"createAt"`         // 消息的创建时间
+}
@@ -0,0 +1,9 @@
+package response
+
+// SessionResponse 结构体用于封装用户在线设备（WebSocket会话）的响应
+type SessionResponse struct {
+	DeviceId    string `json:"deviceId"`    // 设备id，由客户端连接时指定
+	DeviceType  string `json:"deviceType"`  // 设备类型：web、desktop或mobile
+	RemoteAddr  string `json:"remoteAddr"`  // 连接的远端地址
+	ConnectedAt int64  `json:"connectedAt"` // 建立连接的时间（Unix时间戳，毫秒）
+}
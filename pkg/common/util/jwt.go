@@ -0,0 +1,56 @@
+package util
+
+import (
+	"chat-room/pkg/errors" // 引入自定义错误包
+	"time"                 // 引入时间包，用于计算token过期时间
+
+	"github.com/golang-jwt/jwt/v4" // 引入JWT库，用于签发和校验token
+)
+
+// Claims 结构体表示token中携带的声明信息
+type Claims struct {
+	Uuid      string `json:"uuid"`      // 用户的uuid
+	DeviceId  string `json:"deviceId"`  // token绑定的设备id
+	Version   int32  `json:"version"`   // 设备的token版本，设备被远程下线后版本增加，旧token失效
+	TokenType string `json:"tokenType"` // token类型：access或refresh
+	jwt.RegisteredClaims
+}
+
+// GenerateToken 函数使用HMAC-SHA256为用户的设备签发token，返回token字符串和过期时间
+func GenerateToken(uuid, deviceId string, version int32, tokenType, secret string, expire time.Duration) (string, time.Time, error) {
+	expireAt := time.Now().Add(expire) // 计算过期时间
+	claims := Claims{
+		Uuid:      uuid,
+		DeviceId:  deviceId,
+		Version:   version,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expireAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		return "", expireAt, err
+	}
+	return token, expireAt, nil
+}
+
+// ParseToken 函数校验token的签名和有效期，并检查token类型是否与期望一致
+func ParseToken(tokenString, tokenType, secret string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok { // 只接受HMAC签名，防止算法替换攻击
+			return nil, errors.New("不支持的签名算法")
+		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("token无效或已过期")
+	}
+	if claims.TokenType != tokenType || claims.Uuid == "" || claims.DeviceId == "" {
+		return nil, errors.New("token类型错误")
+	}
+	return claims, nil
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"crypto/subtle" // 引入subtle包，用于常量时间比较
+	"strings"       // 引入strings包，用于判断哈希前缀
+	"sync"          // 引入sync包，用于只生成一次占位哈希
+
+	"golang.org/x/crypto/bcrypt" // 引入bcrypt库，用于密码哈希
+)
+
+// bcryptPrefixes 是bcrypt哈希值的前缀，用于区分已哈希的密码和历史遗留的明文密码
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// HashPassword 函数使用bcrypt对明文密码进行哈希
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// IsHashedPassword 函数判断数据库中保存的密码是否已经是bcrypt哈希
+func IsHashedPassword(stored string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(stored, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPassword 函数校验密码是否匹配
+// stored 为数据库中保存的密码，可能是bcrypt哈希，也可能是历史遗留的明文
+// 返回值needRehash表示密码匹配但仍是明文存储，调用方应重新哈希后保存
+func CheckPassword(stored, password string) (match bool, needRehash bool) {
+	if stored == "" || password == "" {
+		return false, false
+	}
+	if IsHashedPassword(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil, false
+	}
+
+	// 历史遗留的明文密码，使用常量时间比较，避免时序攻击
+	match = subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	return match, match
+}
+
+// dummyHash 是用户不存在时用于比较的占位哈希，第一次使用时生成，与真实密码哈希的成本相同
+var (
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// CheckDummyPassword 函数在用户不存在时与占位哈希比较一次密码，使响应时间与用户存在时一致，避免通过响应时间枚举用户名
+func CheckDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("chat-room dummy password"), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
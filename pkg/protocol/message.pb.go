@@ -32,6 +32,11 @@ type Message struct {
 	Url                  string   `protobuf:"bytes,9,opt,name=url,proto3" json:"url,omitempty"`
 	FileSuffix           string   `protobuf:"bytes,10,opt,name=fileSuffix,proto3" json:"fileSuffix,omitempty"`
 	File                 []byte   `protobuf:"bytes,11,opt,name=file,proto3" json:"file,omitempty"`
+	Id                   int64    `protobuf:"varint,12,opt,name=id,proto3" json:"id,omitempty"`
+	Timestamp            int64    `protobuf:"varint,13,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ClientMsgId          string   `protobuf:"bytes,14,opt,name=clientMsgId,proto3" json:"clientMsgId,omitempty"`
+	FromDeviceId         string   `protobuf:"bytes,15,opt,name=fromDeviceId,proto3" json:"fromDeviceId,omitempty"`
+	Target               string   `protobuf:"bytes,16,opt,name=target,proto3" json:"target,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -138,6 +143,41 @@ func (m *Message) GetFile() []byte {
 	return nil
 }
 
+func (m *Message) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Message) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Message) GetClientMsgId() string {
+	if m != nil {
+		return m.ClientMsgId
+	}
+	return ""
+}
+
+func (m *Message) GetFromDeviceId() string {
+	if m != nil {
+		return m.FromDeviceId
+	}
+	return ""
+}
+
+func (m *Message) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Message)(nil), "protocol.Message")
 }
@@ -145,19 +185,23 @@ func init() {
 func init() { proto.RegisterFile("protocol/message.proto", fileDescriptor_89254f84d2f8e90f) }
 
 var fileDescriptor_89254f84d2f8e90f = []byte{
-	// 219 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x90, 0xc1, 0x4a, 0xc4, 0x30,
-	0x10, 0x86, 0x49, 0x76, 0xb7, 0xdd, 0x9d, 0x5d, 0x44, 0xe6, 0xb0, 0xcc, 0x49, 0xc2, 0x9e, 0x7a,
-	0xd2, 0x83, 0xcf, 0xe1, 0xa5, 0xea, 0x03, 0xc4, 0x32, 0x91, 0x42, 0xda, 0x94, 0x34, 0x15, 0x7d,
-	0x1c, 0xdf, 0x54, 0x32, 0x6d, 0xa1, 0xde, 0xfe, 0xff, 0xfb, 0xf8, 0x03, 0x13, 0xb8, 0x0e, 0x31,
-	0xa4, 0xd0, 0x04, 0xff, 0xd4, 0xf1, 0x38, 0xda, 0x4f, 0x7e, 0x14, 0x80, 0xc7, 0x95, 0xdf, 0x7e,
-	0x35, 0x94, 0x2f, 0xb3, 0xc3, 0x2b, 0x14, 0xf6, 0xcb, 0x26, 0x1b, 0x49, 0x19, 0x55, 0x9d, 0xea,
-	0xa5, 0xe1, 0x0d, 0x2e, 0x2e, 0x86, 0xee, 0x7d, 0xe4, 0xd8, 0xdb, 0x8e, 0x49, 0x8b, 0xfd, 0xc7,
-	0x10, 0x61, 0x9f, 0x3b, 0xed, 0xc4, 0x49, 0xc6, 0x3b, 0xd0, 0x29, 0xd0, 0x5e, 0x88, 0x4e, 0x01,
-	0x09, 0xca, 0x26, 0xf4, 0x89, 0xfb, 0x44, 0x07, 0x81, 0x6b, 0x45, 0x03, 0xe7, 0x25, 0xbe, 0xfd,
-	0x0c, 0x4c, 0x85, 0x51, 0xd5, 0xa1, 0xde, 0xa2, 0xfc, 0x7e, 0xca, 0xaa, 0x9c, 0xdf, 0xcf, 0x39,
-	0xaf, 0x96, 0xb3, 0x64, 0x75, 0x9c, 0x57, 0x1b, 0x84, 0xf7, 0xb0, 0x9b, 0xa2, 0xa7, 0x93, 0x8c,
-	0x72, 0xc4, 0x07, 0x00, 0xd7, 0x7a, 0x7e, 0x9d, 0x9c, 0x6b, 0xbf, 0x09, 0x44, 0x6c, 0x88, 0xdc,
-	0xd1, 0x7a, 0xa6, 0xb3, 0x51, 0xd5, 0xa5, 0x96, 0xfc, 0x51, 0xc8, 0x6f, 0x3d, 0xff, 0x05, 0x00,
-	0x00, 0xff, 0xff, 0xb2, 0x77, 0xfe, 0xe2, 0x4e, 0x01, 0x00, 0x00,
+	// 283 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x91, 0x3f, 0x6e, 0xb3, 0x40,
+	0x10, 0xc5, 0x05, 0xf8, 0xef, 0x98, 0xcf, 0x9f, 0xb5, 0x85, 0x35, 0x45, 0x14, 0x21, 0x57, 0x54,
+	0x49, 0x91, 0x2b, 0xa4, 0x71, 0xe1, 0x86, 0x24, 0x07, 0xd8, 0xc0, 0x80, 0x56, 0x02, 0x16, 0x2d,
+	0x63, 0x2b, 0x39, 0x54, 0xee, 0x18, 0xed, 0x00, 0x32, 0xe9, 0xde, 0xfc, 0x9e, 0xde, 0xae, 0xde,
+	0x0c, 0x1c, 0x3b, 0x67, 0xd9, 0xe6, 0xb6, 0x7e, 0x6e, 0xa8, 0xef, 0x75, 0x45, 0x4f, 0x02, 0xd4,
+	0x66, 0xe2, 0xa7, 0x9f, 0x08, 0xd6, 0x97, 0xc1, 0x53, 0x47, 0x58, 0xe9, 0x9b, 0x66, 0xed, 0x30,
+	0x48, 0x82, 0x74, 0x9b, 0x8d, 0x93, 0x3a, 0x41, 0x5c, 0x3a, 0xdb, 0x7c, 0xf4, 0xe4, 0x5a, 0xdd,
+	0x10, 0x86, 0xe2, 0xfe, 0x61, 0x4a, 0xc1, 0xc2, 0xcf, 0x18, 0x89, 0x27, 0x5a, 0xed, 0x21, 0x64,
+	0x8b, 0x0b, 0x21, 0x21, 0x5b, 0x85, 0xb0, 0xce, 0x6d, 0xcb, 0xd4, 0x32, 0x2e, 0x05, 0x4e, 0xa3,
+	0x4a, 0x60, 0x37, 0xca, 0xf7, 0xef, 0x8e, 0x70, 0x95, 0x04, 0xe9, 0x32, 0x9b, 0x23, 0xff, 0x3e,
+	0x7b, 0x6b, 0x3d, 0xbc, 0xef, 0xb5, 0x4f, 0x8d, 0xb5, 0x24, 0xb5, 0x19, 0x52, 0x33, 0xa4, 0x0e,
+	0x10, 0x5d, 0x5d, 0x8d, 0x5b, 0x09, 0x79, 0xa9, 0x1e, 0x01, 0x4a, 0x53, 0xd3, 0xdb, 0xb5, 0x2c,
+	0xcd, 0x17, 0x82, 0x18, 0x33, 0x22, 0x3d, 0x4c, 0x4d, 0xb8, 0x4b, 0x82, 0x34, 0xce, 0x44, 0xfb,
+	0x1e, 0xa6, 0xc0, 0x38, 0x09, 0xd2, 0x28, 0x0b, 0x4d, 0xa1, 0x1e, 0x60, 0xcb, 0xa6, 0xa1, 0x9e,
+	0x75, 0xd3, 0xe1, 0x3f, 0xc1, 0x77, 0x20, 0x5d, 0x6a, 0x43, 0x2d, 0x5f, 0xfa, 0xea, 0x5c, 0xe0,
+	0x5e, 0xbe, 0x98, 0xa3, 0x69, 0x9f, 0xaf, 0x74, 0x33, 0x39, 0x9d, 0x0b, 0xfc, 0x7f, 0xdf, 0xe7,
+	0xc4, 0xfc, 0x2d, 0x58, 0xbb, 0x8a, 0x18, 0x0f, 0xc3, 0x2d, 0x86, 0xe9, 0x73, 0x25, 0x97, 0x7b,
+	0xf9, 0x1d, 0x00, 0x94, 0x0f, 0xef, 0xf6, 0xda, 0x01, 0x00, 0x00,
 }
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/internal/bus"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestChannelBus(t *testing.T) {
+	b := bus.NewChannelBus()
+	var received []string
+	b.Subscribe(func(data []byte) {
+		received = append(received, string(data))
+	})
+
+	var results []error
+	b.Publish("a:b", []byte("hello"), func(err error) { results = append(results, err) })
+	b.Publish("a:b", []byte("world"), nil)
+	if len(received) != 2 || received[0] != "hello" || received[1] != "world" {
+		t.Fatalf("received = %v, want [hello world]", received)
+	}
+	if len(results) != 1 || results[0] != nil {
+		t.Fatalf("results = %v, want one successful delivery", results)
+	}
+}
+
+func TestRedisBus(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// 两个节点订阅同一个频道，都应该收到发布的消息
+	node1 := bus.NewRedisBus(redis.NewClient(&redis.Options{Addr: s.Addr()}), "go-chat-message")
+	node2 := bus.NewRedisBus(redis.NewClient(&redis.Options{Addr: s.Addr()}), "go-chat-message")
+	defer node1.Close()
+	defer node2.Close()
+
+	received1 := make(chan string, 10)
+	received2 := make(chan string, 10)
+	if err := node1.Subscribe(func(data []byte) { received1 <- string(data) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := node2.Subscribe(func(data []byte) { received2 <- string(data) }); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, msg := range []string{"first", "second"} {
+		var result error
+		node1.Publish("a:b", []byte(msg), func(err error) { result = err })
+		if result != nil {
+			t.Fatal(result)
+		}
+	}
+
+	for _, received := range []chan string{received1, received2} {
+		for _, want := range []string{"first", "second"} {
+			select {
+			case got := <-received:
+				if got != want {
+					t.Fatalf("got %q, want %q", got, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timeout waiting for %q", want)
+			}
+		}
+	}
+}
+
+func TestRedisBusPublishError(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bus.NewRedisBus(redis.NewClient(&redis.Options{Addr: s.Addr()}), "go-chat-message")
+	defer b.Close()
+	s.Close() // Redis不可用时发布结果应该返回错误，由发送者回复NACK
+
+	var result error
+	b.Publish("a:b", []byte("lost"), func(err error) { result = err })
+	if result == nil {
+		t.Fatal("publish to a closed redis should fail")
+	}
+}
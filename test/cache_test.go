@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/internal/cache"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := cache.NewLRUCache(2, time.Minute)
+	c.Set("a", []byte("1"))
+	c.Set("b", []byte("2"))
+	c.Get("a") // a成为最近使用，写入c时淘汰b
+	c.Set("c", []byte("3"))
+
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Fatalf("%s should still be cached", key)
+		}
+	}
+
+	c.Delete("a", "missing")
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a should have been deleted")
+	}
+}
+
+func TestLRUCacheExpiry(t *testing.T) {
+	c := cache.NewLRUCache(10, 20*time.Millisecond)
+	c.Set("group:members:g1", []byte(`["u1","u2"]`))
+	if value, ok := c.Get("group:members:g1"); !ok || string(value) != `["u1","u2"]` {
+		t.Fatalf("Get = %q, %v", value, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := c.Get("group:members:g1"); ok {
+		t.Fatal("entry should have expired")
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	// 两个节点共享同一个Redis，一个节点删除后另一个节点也读不到旧数据
+	node1 := cache.NewRedisCache(client, "go-chat:cache:", time.Minute)
+	node2 := cache.NewRedisCache(client, "go-chat:cache:", time.Minute)
+
+	node1.Set("user:profile:u1", []byte("alice"))
+	if value, ok := node2.Get("user:profile:u1"); !ok || string(value) != "alice" {
+		t.Fatalf("Get = %q, %v", value, ok)
+	}
+	if ttl := s.TTL("go-chat:cache:user:profile:u1"); ttl != time.Minute {
+		t.Fatalf("ttl = %v, want 1m", ttl)
+	}
+
+	node2.Delete("user:profile:u1")
+	if _, ok := node1.Get("user:profile:u1"); ok {
+		t.Fatal("entry should have been deleted")
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"chat-room/internal/model"
+)
+
+func TestGroupMemberMuted(t *testing.T) {
+	const now = int64(1700000000000)
+	cases := []struct {
+		name   string
+		member model.GroupMember
+		muted  bool
+	}{
+		{"not muted", model.GroupMember{}, false},
+		{"muted forever", model.GroupMember{Mute: 1}, true},
+		{"timed mute active", model.GroupMember{Mute: 1, MuteUntil: now + 1}, true},
+		{"timed mute expired", model.GroupMember{Mute: 1, MuteUntil: now}, false},
+		{"stale until without mute", model.GroupMember{MuteUntil: now + 1}, false},
+	}
+	for _, c := range cases {
+		if muted := c.member.Muted(now); muted != c.muted {
+			t.Errorf("%s: Muted = %v, want %v", c.name, muted, c.muted)
+		}
+	}
+}
+
+func TestGroupInviteUsable(t *testing.T) {
+	const now = int64(1700000000000)
+	cases := []struct {
+		name   string
+		invite model.GroupInvite
+		usable bool
+	}{
+		{"unlimited", model.GroupInvite{}, true},
+		{"not expired", model.GroupInvite{ExpiresAt: now + 1}, true},
+		{"expired", model.GroupInvite{ExpiresAt: now}, false},
+		{"uses left", model.GroupInvite{MaxUses: 2, Uses: 1}, true},
+		{"used up", model.GroupInvite{MaxUses: 2, Uses: 2}, false},
+	}
+	for _, c := range cases {
+		if usable := c.invite.Usable(now); usable != c.usable {
+			t.Errorf("%s: Usable = %v, want %v", c.name, usable, c.usable)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package test
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chat-room/internal/hub"
+)
+
+// loadClient 模拟一个连接：路由worker以非阻塞方式放入发送队列，由独立的协程消费，与Client.Write一致
+type loadClient struct {
+	user     string
+	device   string
+	send     chan []byte
+	received *sync.WaitGroup
+	dropped  *int64
+}
+
+func (c *loadClient) User() string   { return c.user }
+func (c *loadClient) Device() string { return c.device }
+
+func (c *loadClient) push(data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		atomic.AddInt64(c.dropped, 1)
+		c.received.Done()
+	}
+}
+
+func (c *loadClient) write() {
+	for range c.send {
+		c.received.Done()
+	}
+}
+
+type loadMessage struct {
+	to   string
+	data []byte
+}
+
+// runHubLoad 登记users个用户、每个用户devices个设备，由senders个协程并发发送messages条单聊消息，返回每秒投递的消息数
+func runHubLoad(t testing.TB, shards, users, devices, senders, messages int) float64 {
+	registry := hub.NewRegistry(shards)
+	var received sync.WaitGroup
+	var dropped int64
+
+	clients := make([]*loadClient, 0, users*devices)
+	for u := 0; u < users; u++ {
+		for d := 0; d < devices; d++ {
+			client := &loadClient{
+				user:     "user-" + strconv.Itoa(u),
+				device:   "device-" + strconv.Itoa(d),
+				send:     make(chan []byte, 256),
+				received: &received,
+				dropped:  &dropped,
+			}
+			registry.Add(client)
+			clients = append(clients, client)
+			go client.write()
+		}
+	}
+
+	dispatcher := hub.NewDispatcher(shards, 1024, func(item interface{}) {
+		msg := item.(*loadMessage)
+		for _, session := range registry.Devices(msg.to) {
+			session.(*loadClient).push(msg.data)
+		}
+	})
+
+	// 每条消息投递到接收者的所有设备
+	received.Add(messages * devices)
+	start := time.Now()
+	var sent sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		sent.Add(1)
+		go func(s int) {
+			defer sent.Done()
+			r := rand.New(rand.NewSource(int64(s)))
+			from := "user-" + strconv.Itoa(s%users)
+			for i := s; i < messages; i += senders {
+				to := "user-" + strconv.Itoa(r.Intn(users))
+				dispatcher.Dispatch(from+":"+to, &loadMessage{to: to, data: []byte("hello")})
+			}
+		}(s)
+	}
+	sent.Wait()
+	received.Wait()
+	elapsed := time.Since(start)
+
+	dispatcher.Close()
+	for _, client := range clients {
+		close(client.send)
+	}
+	if dropped > 0 {
+		t.Logf("dropped %d messages on full send queues", dropped)
+	}
+	return float64(messages*devices) / elapsed.Seconds()
+}
+
+func TestHubLoad(t *testing.T) {
+	users, messages := 5000, 200000
+	if testing.Short() {
+		users, messages = 1000, 20000
+	}
+
+	for _, shards := range []int{1, 4, 16} {
+		rate := runHubLoad(t, shards, users, 2, 64, messages)
+		t.Logf("shards=%d clients=%d messages=%d: %.0f deliveries/s", shards, users*2, messages, rate)
+	}
+}
+
+// TestHubRegistryConcurrent 在路由消息的同时不断注册和注销连接，配合-race检查注册表的并发安全
+func TestHubRegistryConcurrent(t *testing.T) {
+	registry := hub.NewRegistry(4)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				client := &loadClient{user: fmt.Sprintf("user-%d", n%100), device: fmt.Sprintf("device-%d", i)}
+				registry.Add(client)
+				registry.Devices(client.user)
+				registry.Remove(client)
+			}
+		}(i)
+	}
+
+	for i := 0; i < 1000; i++ {
+		registry.Range(func(session hub.Session) {})
+		registry.Len()
+	}
+	close(stop)
+	wg.Wait()
+
+	if n := registry.Len(); n != 0 {
+		t.Fatalf("registry.Len() = %d after all clients removed, want 0", n)
+	}
+}
+
+func TestHubRegistryReplace(t *testing.T) {
+	registry := hub.NewRegistry(4)
+	first := &loadClient{user: "alice", device: "web"}
+	second := &loadClient{user: "alice", device: "web"}
+	mobile := &loadClient{user: "alice", device: "mobile"}
+
+	if old, firstDevice := registry.Add(first); old != nil || !firstDevice {
+		t.Fatalf("Add(first) = %v, %v, want nil, true", old, firstDevice)
+	}
+	if old, firstDevice := registry.Add(second); old != first || !firstDevice {
+		t.Fatalf("Add(second) = %v, %v, want first, true", old, firstDevice)
+	}
+	if _, firstDevice := registry.Add(mobile); firstDevice {
+		t.Fatal("Add(mobile) reported the first device")
+	}
+	// 被替换的旧连接注销时不能删除新连接
+	if removed, _ := registry.Remove(first); removed || len(registry.Devices("alice")) != 2 {
+		t.Fatal("removing a replaced session changed the registry")
+	}
+	if removed, lastDevice := registry.Remove(second); !removed || lastDevice {
+		t.Fatalf("Remove(second) = %v, %v, want true, false", removed, lastDevice)
+	}
+	if removed, lastDevice := registry.Remove(mobile); !removed || !lastDevice {
+		t.Fatalf("Remove(mobile) = %v, %v, want true, true", removed, lastDevice)
+	}
+}
+
+func TestHubRegistryForEach(t *testing.T) {
+	registry := hub.NewRegistry(8)
+	var members []string
+	for u := 0; u < 100; u++ {
+		user := "user-" + strconv.Itoa(u)
+		members = append(members, user)
+		registry.Add(&loadClient{user: user, device: "web"})
+		registry.Add(&loadClient{user: user, device: "mobile"})
+	}
+	members = append(members, "offline-user")
+
+	visited := make(map[string]int)
+	registry.ForEach(members, func(session hub.Session) {
+		visited[session.User()+"/"+session.Device()]++
+	})
+	if len(visited) != 200 {
+		t.Fatalf("visited %d sessions, want 200", len(visited))
+	}
+	for key, n := range visited {
+		if n != 1 {
+			t.Fatalf("%s visited %d times", key, n)
+		}
+	}
+}
+
+// TestHubDispatchOrder 同一个key的消息按发送顺序处理
+func TestHubDispatchOrder(t *testing.T) {
+	var mutex sync.Mutex
+	got := make(map[string][]int)
+	dispatcher := hub.NewDispatcher(8, 16, func(item interface{}) {
+		msg := item.([2]interface{})
+		mutex.Lock()
+		got[msg[0].(string)] = append(got[msg[0].(string)], msg[1].(int))
+		mutex.Unlock()
+	})
+	for i := 0; i < 1000; i++ {
+		key := "conversation-" + strconv.Itoa(i%10)
+		dispatcher.Dispatch(key, [2]interface{}{key, i})
+	}
+	dispatcher.Close()
+
+	for key, ids := range got {
+		for i := 1; i < len(ids); i++ {
+			if ids[i] <= ids[i-1] {
+				t.Fatalf("%s processed out of order: %v", key, ids)
+			}
+		}
+	}
+}
+
+func BenchmarkHubDispatch(b *testing.B) {
+	rate := runHubLoad(b, runtime.NumCPU(), 5000, 2, 64, b.N)
+	b.ReportMetric(rate, "deliveries/s")
+}
+
+// BenchmarkHubGroupFanout 一条群消息扇出给5000个成员，所有成员共享同一份消息，分配次数与成员数无关
+func BenchmarkHubGroupFanout(b *testing.B) {
+	registry := hub.NewRegistry(runtime.NumCPU())
+	members := make([]string, 5000)
+	for u := range members {
+		members[u] = "user-" + strconv.Itoa(u)
+		registry.Add(&loadClient{user: members[u], device: "web", send: make(chan []byte, 1)})
+	}
+	data := []byte("group message")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		registry.ForEach(members, func(session hub.Session) {
+			client := session.(*loadClient)
+			select {
+			case client.send <- data:
+			default:
+			}
+			select {
+			case <-client.send:
+			default:
+			}
+		})
+	}
+}
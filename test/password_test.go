@@ -0,0 +1,28 @@
+package test
+
+import (
+	"testing"
+
+	"chat-room/pkg/common/util"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hashed, err := util.HashPassword("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match, needRehash := util.CheckPassword(hashed, "123456"); !match || needRehash {
+		t.Fatalf("hashed password: match=%v needRehash=%v", match, needRehash)
+	}
+	if match, _ := util.CheckPassword(hashed, "654321"); match {
+		t.Fatal("wrong password matched")
+	}
+
+	// 历史遗留的明文密码可以登录，并提示需要重新哈希
+	if match, needRehash := util.CheckPassword("123456", "123456"); !match || !needRehash {
+		t.Fatalf("legacy password: match=%v needRehash=%v", match, needRehash)
+	}
+	if match, _ := util.CheckPassword("", ""); match {
+		t.Fatal("empty password matched")
+	}
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/internal/search"
+)
+
+func TestMemoryIndexSearch(t *testing.T) {
+	index := search.NewMemoryIndex()
+	index.Index(search.Document{MessageId: 1, MessageType: 1, FromUserId: 1, ToUserId: 2, Content: "明天一起去吃火锅"})
+	index.Index(search.Document{MessageId: 2, MessageType: 1, FromUserId: 3, ToUserId: 4, Content: "火锅太辣了"})
+	index.Index(search.Document{MessageId: 3, MessageType: 2, FromUserId: 3, ToUserId: 10, Content: "Hotpot tonight?"})
+	index.Index(search.Document{MessageId: 4, MessageType: 2, FromUserId: 3, ToUserId: 11, Content: "吃火锅吗"})
+
+	// 用户2只能看到自己参与的单聊和所在群组10的消息
+	hits, err := index.Search(search.Query{Keyword: "火锅", UserId: 2, Groups: []search.Membership{{GroupId: 10}}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].MessageId != 1 {
+		t.Fatalf("hits = %+v, want message 1", hits)
+	}
+
+	hits, _ = index.Search(search.Query{Keyword: "HOTPOT", UserId: 2, Groups: []search.Membership{{GroupId: 10}, {GroupId: 11}}, Limit: 10})
+	if len(hits) != 1 || hits[0].MessageId != 3 {
+		t.Fatalf("hits = %+v, want message 3", hits)
+	}
+
+	hits, _ = index.Search(search.Query{Keyword: "火锅", UserId: 2, Groups: []search.Membership{{GroupId: 10}, {GroupId: 11}}, Limit: 10})
+	if len(hits) != 2 || hits[0].MessageId != 4 || hits[1].MessageId != 1 {
+		t.Fatalf("hits = %+v, want messages 4 and 1", hits)
+	}
+}
+
+func TestMemoryIndexSearchJoinedAt(t *testing.T) {
+	index := search.NewMemoryIndex()
+	joinedAt := time.Now()
+	index.Index(search.Document{MessageId: 1, MessageType: 2, FromUserId: 3, ToUserId: 10, Content: "吃火锅吗", CreatedAt: joinedAt.Add(-time.Minute)})
+	index.Index(search.Document{MessageId: 2, MessageType: 2, FromUserId: 3, ToUserId: 10, Content: "火锅店到了", CreatedAt: joinedAt.Add(time.Minute)})
+
+	// 只能检索加入群组之后的消息
+	hits, _ := index.Search(search.Query{Keyword: "火锅", UserId: 2, Groups: []search.Membership{{GroupId: 10, JoinedAt: joinedAt}}, Limit: 10})
+	if len(hits) != 1 || hits[0].MessageId != 2 {
+		t.Fatalf("hits = %+v, want message 2", hits)
+	}
+}
+
+func TestMemoryIndexRemove(t *testing.T) {
+	index := search.NewMemoryIndex()
+	index.Index(search.Document{MessageId: 1, MessageType: 1, FromUserId: 1, ToUserId: 2, Content: "明天一起去吃火锅"})
+
+	// 编辑后旧内容不再命中，新内容可以检索到
+	index.Remove(1)
+	index.Index(search.Document{MessageId: 1, MessageType: 1, FromUserId: 1, ToUserId: 2, Content: "明天一起去爬山"})
+	if hits, _ := index.Search(search.Query{Keyword: "火锅", UserId: 2, Limit: 10}); len(hits) != 0 {
+		t.Fatalf("hits = %+v, want none", hits)
+	}
+	if hits, _ := index.Search(search.Query{Keyword: "爬山", UserId: 2, Limit: 10}); len(hits) != 1 {
+		t.Fatalf("hits = %+v, want message 1", hits)
+	}
+
+	// 撤回后不再命中
+	index.Remove(1)
+	if hits, _ := index.Search(search.Query{Keyword: "爬山", UserId: 2, Limit: 10}); len(hits) != 0 {
+		t.Fatalf("hits = %+v, want none", hits)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	snippet, highlight := search.Highlight("今天<b>火锅</b>店排队，火锅很好吃", "火锅", 6)
+	if snippet != "今天<b>火锅</b>店排..." {
+		t.Fatalf("snippet = %s", snippet)
+	}
+	if highlight != "今天&lt;b&gt;<em>火锅</em>&lt;/b&gt;店排..." {
+		t.Fatalf("highlight = %s", highlight)
+	}
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/pkg/common/constant"
+	"chat-room/pkg/common/util"
+)
+
+func TestGenerateAndParseToken(t *testing.T) {
+	token, _, err := util.GenerateToken("user-uuid", "device-id", 3, constant.ACCESS_TOKEN, "secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := util.ParseToken(token, constant.ACCESS_TOKEN, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Uuid != "user-uuid" {
+		t.Fatalf("uuid = %s, want user-uuid", claims.Uuid)
+	}
+	if claims.DeviceId != "device-id" || claims.Version != 3 {
+		t.Fatalf("device = %s version = %d, want device-id 3", claims.DeviceId, claims.Version)
+	}
+
+	if _, err := util.ParseToken(token, constant.REFRESH_TOKEN, "secret"); err == nil {
+		t.Fatal("access token accepted as refresh token")
+	}
+	if _, err := util.ParseToken(token, constant.ACCESS_TOKEN, "other-secret"); err == nil {
+		t.Fatal("token accepted with wrong secret")
+	}
+
+	noDevice, _, _ := util.GenerateToken("user-uuid", "", 0, constant.ACCESS_TOKEN, "secret", time.Minute)
+	if _, err := util.ParseToken(noDevice, constant.ACCESS_TOKEN, "secret"); err == nil {
+		t.Fatal("token without device accepted")
+	}
+
+	expired, _, _ := util.GenerateToken("user-uuid", "device-id", 3, constant.ACCESS_TOKEN, "secret", -time.Minute)
+	if _, err := util.ParseToken(expired, constant.ACCESS_TOKEN, "secret"); err == nil {
+		t.Fatal("expired token accepted")
+	}
+}